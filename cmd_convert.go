@@ -0,0 +1,47 @@
+//
+// Copyright (c) 2024 Markku Rossi
+//
+// All rights reserved.
+//
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/markkurossi/calc/eval"
+)
+
+func cmdConvert() error {
+	expr, err := eval.NewParser(input).Parse()
+	if err != nil {
+		return err
+	}
+	t, err := input.GetToken()
+	if err != nil {
+		return err
+	}
+	if t.Type != eval.TIdentifier || t.StrVal != "to" {
+		return eval.NewError(t.Column, fmt.Errorf("unexpected token '%s'", t))
+	}
+	unit, err := eval.NewParser(input).ParseUnit()
+	if err != nil {
+		return err
+	}
+	err = input.ExpectEOL()
+	if err != nil {
+		return err
+	}
+
+	val, err := expr.Eval(env)
+	if err != nil {
+		return err
+	}
+	val, err = eval.Convert(val, unit)
+	if err != nil {
+		return eval.NewError(t.Column, err)
+	}
+	fmt.Printf("%s\n", val.Format(env.Config().Options()))
+
+	return nil
+}
@@ -0,0 +1,23 @@
+//
+// Copyright (c) 2024 Markku Rossi
+//
+// All rights reserved.
+//
+
+package main
+
+import (
+	"github.com/markkurossi/calc/eval"
+)
+
+func cmdDefine() error {
+	f, err := eval.NewParser(input).ParseDefinition()
+	if err != nil {
+		return err
+	}
+	err = input.ExpectEOL()
+	if err != nil {
+		return err
+	}
+	return env.Define(f)
+}
@@ -0,0 +1,317 @@
+//
+// Copyright (c) 2024 Markku Rossi
+//
+// All rights reserved.
+//
+
+package main
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/markkurossi/calc/eval"
+	"github.com/markkurossi/tabulate"
+	"github.com/mattn/go-runewidth"
+	"golang.org/x/text/unicode/runenames"
+)
+
+func cmdEncode() error {
+	expr, err := eval.NewParser(input).Parse()
+	if err != nil {
+		return err
+	}
+	val, err := expr.Eval(env)
+	if err != nil {
+		return err
+	}
+	str, err := encodeString(val)
+	if err != nil {
+		return err
+	}
+
+	tab := tabulate.New(tabulate.Simple)
+	tab.Header("Encoding").SetAlign(tabulate.MR)
+	tab.Header("Value").SetAlign(tabulate.ML)
+
+	for _, enc := range []struct {
+		name  string
+		value string
+	}{
+		{"UTF-8", hexBytes([]byte(str))},
+		{"UTF-16LE", hexUnits(utf16Units(str), 16, false)},
+		{"UTF-16BE", hexUnits(utf16Units(str), 16, true)},
+		{"UTF-32LE", hexUnits(utf32(str), 32, false)},
+		{"UTF-32BE", hexUnits(utf32(str), 32, true)},
+		{"Width", strconv.Itoa(runewidth.StringWidth(str))},
+		{"Go", strconv.QuoteToASCII(str)},
+		{"C", escapeC(str)},
+		{"JSON", escapeJSON(str)},
+		{"HTML", escapeHTML(str)},
+		{"URL path", url.PathEscape(str)},
+		{"URL query", url.QueryEscape(str)},
+	} {
+		row := tab.Row()
+		row.Column(enc.name)
+		row.Column(enc.value)
+	}
+	tab.Print(os.Stdout)
+	fmt.Println()
+
+	tab = tabulate.New(tabulate.Simple)
+	tab.Header("Rune").SetAlign(tabulate.ML)
+	tab.Header("Code").SetAlign(tabulate.ML)
+	tab.Header("Name").SetAlign(tabulate.ML)
+	tab.Header("Category").SetAlign(tabulate.ML)
+	tab.Header("Block").SetAlign(tabulate.ML)
+	tab.Header("Script").SetAlign(tabulate.ML)
+	tab.Header("Width").SetAlign(tabulate.MR)
+
+	for _, r := range str {
+		row := tab.Row()
+		if unicode.IsGraphic(r) {
+			row.Column(string(r))
+		} else {
+			row.Column("")
+		}
+		row.Column(fmt.Sprintf("U+%04X", r))
+		row.Column(runenames.Name(r))
+		row.Column(runeCategory(r))
+		row.Column(runeBlock(r))
+		row.Column(runeScript(r))
+		row.Column(strconv.Itoa(runewidth.RuneWidth(r)))
+	}
+	tab.Print(os.Stdout)
+
+	return nil
+}
+
+// encodeString returns the string to encode. Strings are encoded as
+// is and integers are encoded as Unicode code points.
+func encodeString(v eval.Value) (string, error) {
+	if str, ok := v.(eval.StringValue); ok {
+		return string(str), nil
+	}
+	i, err := eval.ValueBigInt(v)
+	if err != nil {
+		return "", fmt.Errorf("encode: expected string or code point: %s",
+			v.Type())
+	}
+	if !i.IsInt64() || !utf8.ValidRune(rune(i.Int64())) ||
+		int64(rune(i.Int64())) != i.Int64() {
+		return "", fmt.Errorf("encode: invalid code point %s", i)
+	}
+	return string(rune(i.Int64())), nil
+}
+
+func hexBytes(data []byte) string {
+	var parts []string
+	for _, b := range data {
+		parts = append(parts, fmt.Sprintf("%02x", b))
+	}
+	return strings.Join(parts, " ")
+}
+
+// hexUnits formats the code units of bits bits as bytes in the big or
+// little endian byte order.
+func hexUnits(units []uint32, bits uint, bigEndian bool) string {
+	var data []byte
+	for _, u := range units {
+		for i := uint(0); i < bits; i += 8 {
+			shift := i
+			if bigEndian {
+				shift = bits - 8 - i
+			}
+			data = append(data, byte(u>>shift))
+		}
+	}
+	return hexBytes(data)
+}
+
+func utf16Units(str string) []uint32 {
+	var result []uint32
+	for _, u := range utf16.Encode([]rune(str)) {
+		result = append(result, uint32(u))
+	}
+	return result
+}
+
+func utf32(str string) []uint32 {
+	var result []uint32
+	for _, r := range str {
+		result = append(result, uint32(r))
+	}
+	return result
+}
+
+// escapeC escapes the string as a C string literal. The non-ASCII
+// bytes are escaped as octal escapes since they always have three
+// digits.
+func escapeC(str string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for i := 0; i < len(str); i++ {
+		b := str[i]
+		switch b {
+		case '\a':
+			sb.WriteString(`\a`)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\v':
+			sb.WriteString(`\v`)
+		case '\\', '"':
+			sb.WriteByte('\\')
+			sb.WriteByte(b)
+		default:
+			if b < 0x20 || b >= 0x7f {
+				fmt.Fprintf(&sb, "\\%03o", b)
+			} else {
+				sb.WriteByte(b)
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// escapeJSON escapes the string as an ASCII JSON string. The
+// characters outside the BMP are escaped as UTF-16 surrogate pairs.
+func escapeJSON(str string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range str {
+		switch r {
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\\', '"':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		default:
+			if r < 0x20 || r >= 0x7f {
+				for _, u := range utf16.Encode([]rune{r}) {
+					fmt.Fprintf(&sb, `\u%04x`, u)
+				}
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// escapeHTML escapes the HTML special characters and the non-ASCII
+// characters as numeric character references.
+func escapeHTML(str string) string {
+	var sb strings.Builder
+	for _, r := range str {
+		if r >= 0x80 {
+			fmt.Fprintf(&sb, "&#x%x;", r)
+		} else {
+			sb.WriteString(html.EscapeString(string(r)))
+		}
+	}
+	return sb.String()
+}
+
+var categoryNames = map[string]string{
+	"Cc": "Control",
+	"Cf": "Format",
+	"Co": "Private Use",
+	"Cs": "Surrogate",
+	"Ll": "Lowercase Letter",
+	"Lm": "Modifier Letter",
+	"Lo": "Other Letter",
+	"Lt": "Titlecase Letter",
+	"Lu": "Uppercase Letter",
+	"Mc": "Spacing Mark",
+	"Me": "Enclosing Mark",
+	"Mn": "Nonspacing Mark",
+	"Nd": "Decimal Number",
+	"Nl": "Letter Number",
+	"No": "Other Number",
+	"Pc": "Connector Punctuation",
+	"Pd": "Dash Punctuation",
+	"Pe": "Close Punctuation",
+	"Pf": "Final Punctuation",
+	"Pi": "Initial Punctuation",
+	"Po": "Other Punctuation",
+	"Ps": "Open Punctuation",
+	"Sc": "Currency Symbol",
+	"Sk": "Modifier Symbol",
+	"Sm": "Math Symbol",
+	"So": "Other Symbol",
+	"Zl": "Line Separator",
+	"Zp": "Paragraph Separator",
+	"Zs": "Space Separator",
+}
+
+// runeCategory returns the Unicode general category of the rune.
+func runeCategory(r rune) string {
+	for cat, name := range categoryNames {
+		table, ok := unicode.Categories[cat]
+		if ok && unicode.Is(table, r) {
+			return cat + " " + name
+		}
+	}
+	return "Cn Unassigned"
+}
+
+// runeScript returns the Unicode script of the rune.
+func runeScript(r rune) string {
+	var names []string
+	for name := range unicode.Scripts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if unicode.Is(unicode.Scripts[name], r) {
+			return name
+		}
+	}
+	return "Unknown"
+}
+
+//go:generate go run gen_blocks.go -version 14.0.0
+
+// unicodeBlock defines a Unicode block.
+type unicodeBlock struct {
+	first rune
+	last  rune
+	name  string
+}
+
+// runeBlock returns the Unicode block of the rune.
+func runeBlock(r rune) string {
+	idx := sort.Search(len(unicodeBlocks), func(i int) bool {
+		return unicodeBlocks[i].last >= r
+	})
+	if idx < len(unicodeBlocks) && unicodeBlocks[idx].first <= r {
+		return unicodeBlocks[idx].name
+	}
+	return "No Block"
+}
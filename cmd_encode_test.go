@@ -0,0 +1,105 @@
+//
+// Copyright (c) 2024 Markku Rossi
+//
+// All rights reserved.
+//
+
+package main
+
+import (
+	"testing"
+)
+
+var escapeTests = []struct {
+	in   string
+	c    string
+	json string
+	html string
+}{
+	{
+		in:   "a\tb",
+		c:    `"a\tb"`,
+		json: `"a\tb"`,
+		html: "a\tb",
+	},
+	{
+		in:   `"<é>"`,
+		c:    `"\"<\303\251>\""`,
+		json: `"\"<\u00e9>\""`,
+		html: "&#34;&lt;&#xe9;&gt;&#34;",
+	},
+	{
+		in:   "😀",
+		c:    `"\360\237\230\200"`,
+		json: `"\ud83d\ude00"`,
+		html: "&#x1f600;",
+	},
+}
+
+func TestEscape(t *testing.T) {
+	for idx, test := range escapeTests {
+		if out := escapeC(test.in); out != test.c {
+			t.Errorf("test %d: escapeC: got %s, expected %s", idx, out, test.c)
+		}
+		if out := escapeJSON(test.in); out != test.json {
+			t.Errorf("test %d: escapeJSON: got %s, expected %s",
+				idx, out, test.json)
+		}
+		if out := escapeHTML(test.in); out != test.html {
+			t.Errorf("test %d: escapeHTML: got %s, expected %s",
+				idx, out, test.html)
+		}
+	}
+}
+
+func TestUnits(t *testing.T) {
+	if out := hexUnits(utf16Units("é😀"), 16, true); out != "00 e9 d8 3d de 00" {
+		t.Errorf("UTF-16BE: got %s", out)
+	}
+	if out := hexUnits(utf32("é"), 32, false); out != "e9 00 00 00" {
+		t.Errorf("UTF-32LE: got %s", out)
+	}
+}
+
+var runeInfoTests = []struct {
+	r        rune
+	category string
+	block    string
+	script   string
+}{
+	{'A', "Lu Uppercase Letter", "Basic Latin", "Latin"},
+	{'€', "Sc Currency Symbol", "Currency Symbols", "Common"},
+	{'ж', "Ll Lowercase Letter", "Cyrillic", "Cyrillic"},
+	{'中', "Lo Other Letter", "CJK Unified Ideographs", "Han"},
+	{0x1f600, "So Other Symbol", "Emoticons", "Common"},
+	{0x0378, "Cn Unassigned", "Greek and Coptic", "Unknown"},
+	{0x1e900, "Lu Uppercase Letter", "Adlam", "Adlam"},
+	{0x11700, "Lo Other Letter", "Ahom", "Ahom"},
+	{0x2fe0, "Cn Unassigned", "No Block", "Unknown"},
+}
+
+func TestUnicodeBlocks(t *testing.T) {
+	var prev rune = -1
+	for idx, block := range unicodeBlocks {
+		if block.first <= prev || block.last < block.first {
+			t.Errorf("block %d: %s %04X..%04X out of order",
+				idx, block.name, block.first, block.last)
+		}
+		prev = block.last
+	}
+}
+
+func TestRuneInfo(t *testing.T) {
+	for idx, test := range runeInfoTests {
+		if out := runeCategory(test.r); out != test.category {
+			t.Errorf("test %d: category %s, expected %s",
+				idx, out, test.category)
+		}
+		if out := runeBlock(test.r); out != test.block {
+			t.Errorf("test %d: block %s, expected %s", idx, out, test.block)
+		}
+		if out := runeScript(test.r); out != test.script {
+			t.Errorf("test %d: script %s, expected %s", idx, out, test.script)
+		}
+	}
+}
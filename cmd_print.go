@@ -1,5 +1,5 @@
 //
-// Copyright (c) 2020 Markku Rossi
+// Copyright (c) 2020, 2024 Markku Rossi
 //
 // All rights reserved.
 //
@@ -8,16 +8,21 @@ package main
 
 import (
 	"fmt"
+	"math/big"
 	"os"
+	"time"
 	"unicode"
 
+	"github.com/markkurossi/calc/eval"
 	"github.com/markkurossi/tabulate"
 )
 
 func cmdPrint() error {
-	base := Base10
+	options := env.Config().Options()
 	asCharacter := false
-	asString := false
+	asAddress := false
+	asMAC := false
+	asTime := false
 
 	t, err := input.GetToken()
 	if err != nil {
@@ -29,62 +34,137 @@ func cmdPrint() error {
 		if err != nil {
 			return err
 		}
-		if t.Type != TIdentifier {
-			return NewError(t.Column, fmt.Errorf("unexpected token '%s'", t))
+		if t.Type != eval.TIdentifier {
+			return eval.NewError(t.Column,
+				fmt.Errorf("unexpected token '%s'", t))
 		}
-		switch t.StrVal {
-		case "b":
-			base = Base2
-
-		case "o":
-			base = Base8
-
-		case "x":
-			base = Base16
-
-		case "t":
-			base = BaseBinary
-
-		case "c":
+		if t.StrVal == "c" {
 			asCharacter = true
-
-		case "s":
-			asString = true
-
-		default:
-			return NewError(t.Column, fmt.Errorf("unknown option '%s'", t))
+		} else if t.StrVal == "ip" {
+			asAddress = true
+		} else if t.StrVal == "mac" {
+			asMAC = true
+		} else if t.StrVal == "time" {
+			asTime = true
+		} else {
+			options, err = formatOptions(options, t.StrVal)
+			if err != nil {
+				return eval.NewError(t.Column, err)
+			}
+		}
+		t, err = input.GetToken()
+		if err != nil {
+			return err
 		}
-	} else {
-		input.UngetToken(t)
 	}
+	col := t.Column
+	input.UngetToken(t)
 
-	expr, err := parseExpr()
+	expr, err := eval.NewParser(input).Parse()
+	if err != nil {
+		return err
+	}
+	err = input.ExpectEOL()
 	if err != nil {
 		return err
 	}
 
-	val, err := expr.Eval()
+	val, err := expr.Eval(env)
 	if err != nil {
 		return err
 	}
 
-	if asCharacter {
-		return printAsCharacter(val)
+	switch {
+	case asCharacter:
+		err = printAsCharacter(val)
+	case asAddress:
+		err = printAsAddress(val)
+	case asMAC:
+		err = printAsMAC(val)
+	case asTime:
+		err = printAsTime(val)
+	default:
+		err = eval.CheckFormat(val, options)
+		if err == nil {
+			fmt.Printf("%s\n", val.Format(options))
+		}
 	}
-	if asString {
-		return printAsString(val)
+	if err != nil {
+		return eval.NewError(col, err)
 	}
-	fmt.Printf("%s\n", val.Format(Options{
-		Base: base,
-	}))
-
 	return nil
 }
 
-func printAsCharacter(v Value) error {
-	r, err := ValueInt32(v)
+// formatOptions sets the value output options for the print format
+// name.
+func formatOptions(options eval.Options, format string) (
+	eval.Options, error) {
+
+	switch format {
+	case "b":
+		options.Base = eval.Base2
+
+	case "o":
+		options.Base = eval.Base8
+
+	case "x":
+		options.Base = eval.Base16
+
+	case "t":
+		options.Base = eval.BaseBinary
+
+	case "a":
+		options.Base = eval.BaseHexFloat
+
+	case "s":
+		options.Base = eval.Base8
+		options.String = true
+
+	case "h":
+		options.Human = true
+
+	case "ht":
+		options.Human = true
+		options.HumanTime = true
+
+	case "q":
+		options.Raw = true
+
+	case "hex", "base64", "base32", "url", "qp":
+		enc, err := eval.LookupEncoding(format)
+		if err != nil {
+			return options, err
+		}
+		options.Encoding = enc
+
+	case "colon":
+		options.MAC = eval.MACColon
+
+	case "dash":
+		options.MAC = eval.MACDash
+
+	case "dot":
+		options.MAC = eval.MACDot
+
+	case "bare":
+		options.MAC = eval.MACBare
+
+	case "rect":
+		options.Complex = eval.ComplexRect
+
+	case "polar":
+		options.Complex = eval.ComplexPolar
+
+	default:
+		return options, fmt.Errorf("unknown option '%s'", format)
+	}
+	return options, nil
+}
+
+func printAsCharacter(v eval.Value) error {
+	r, err := eval.ValueInt32(v)
 	if err != nil {
-		return err
+		return fmt.Errorf("%s value is not a character", v.Type())
 	}
 
 	tab := tabulate.New(tabulate.Simple)
@@ -119,10 +199,168 @@ func printAsCharacter(v Value) error {
 	return nil
 }
 
-func printAsString(v Value) error {
-	fmt.Println(v.Format(Options{
-		Base:   Base8,
-		String: true,
-	}))
+func printAsAddress(v eval.Value) error {
+	ip, err := eval.ValueIP(v)
+	if err != nil {
+		return err
+	}
+	addr := ip.Addr()
+
+	tab := tabulate.New(tabulate.Simple)
+	tab.Header("Format").SetAlign(tabulate.MR)
+	tab.Header("Value").SetAlign(tabulate.ML)
+
+	row := tab.Row()
+	row.Column("Address")
+	row.Column(addr.String())
+
+	row = tab.Row()
+	row.Column("Hex")
+	row.Column(fmt.Sprintf("0x%0*x", addr.Bits()/4, addr.Int()))
+
+	row = tab.Row()
+	row.Column("Integer")
+	row.Column(addr.Int().String())
+
+	if ip.Prefix() >= 0 {
+		row = tab.Row()
+		row.Column("Prefix")
+		row.Column(fmt.Sprintf("/%d", ip.Prefix()))
+
+		row = tab.Row()
+		row.Column("Netmask")
+		row.Column(ip.Netmask().String())
+
+		row = tab.Row()
+		row.Column("Network")
+		row.Column(ip.Network().String())
+
+		row = tab.Row()
+		if addr.Bits() == 32 {
+			row.Column("Broadcast")
+		} else {
+			row.Column("Last")
+		}
+		row.Column(ip.Broadcast().String())
+
+		row = tab.Row()
+		row.Column("Hosts")
+		row.Column(ip.Hosts().String())
+	}
+	tab.Print(os.Stdout)
+
+	return nil
+}
+
+func printAsMAC(v eval.Value) error {
+	mac, err := eval.ValueMAC(v)
+	if err != nil {
+		return err
+	}
+
+	tab := tabulate.New(tabulate.Simple)
+	tab.Header("Format").SetAlign(tabulate.MR)
+	tab.Header("Value").SetAlign(tabulate.ML)
+
+	for _, f := range []struct {
+		name   string
+		format eval.MACFormat
+	}{
+		{"Colon", eval.MACColon},
+		{"Dash", eval.MACDash},
+		{"Dot", eval.MACDot},
+		{"Bare", eval.MACBare},
+	} {
+		row := tab.Row()
+		row.Column(f.name)
+		row.Column(mac.Notation(f.format))
+	}
+
+	row := tab.Row()
+	row.Column("Integer")
+	row.Column(mac.Int().String())
+
+	row = tab.Row()
+	row.Column("OUI")
+	row.Column(fmt.Sprintf("%06x", mac.OUI()))
+
+	row = tab.Row()
+	row.Column("Octet 0")
+	row.Column(fmt.Sprintf("%08b", mac.Bytes()[0]))
+
+	row = tab.Row()
+	row.Column("I/G bit")
+	if mac.Multicast() {
+		row.Column("1 group (multicast)")
+	} else {
+		row.Column("0 individual (unicast)")
+	}
+
+	row = tab.Row()
+	row.Column("U/L bit")
+	if mac.Local() {
+		row.Column("1 locally administered")
+	} else {
+		row.Column("0 universally administered")
+	}
+
+	if eui64, err := mac.EUI64(); err == nil {
+		row = tab.Row()
+		row.Column("EUI-64")
+		row.Column(eui64.String())
+
+		ll, _ := mac.LinkLocal()
+		row = tab.Row()
+		row.Column("Link-local")
+		row.Column(ll.String())
+	}
+	tab.Print(os.Stdout)
+
+	return nil
+}
+
+func printAsTime(v eval.Value) error {
+	tv, err := eval.ValueTime(v)
+	if err != nil {
+		return err
+	}
+	t := tv.Time().In(env.Config().Location)
+	ns := new(big.Int).Mul(big.NewInt(t.Unix()), big.NewInt(1000000000))
+	ns.Add(ns, big.NewInt(int64(t.Nanosecond())))
+
+	tab := tabulate.New(tabulate.Simple)
+	tab.Header("Format").SetAlign(tabulate.MR)
+	tab.Header("Value").SetAlign(tabulate.ML)
+
+	row := tab.Row()
+	row.Column("Time")
+	row.Column(t.Format(time.RFC3339Nano))
+
+	row = tab.Row()
+	row.Column("UTC")
+	row.Column(t.UTC().Format(time.RFC3339Nano))
+
+	row = tab.Row()
+	row.Column("RFC 1123")
+	row.Column(t.Format(time.RFC1123))
+
+	row = tab.Row()
+	row.Column("Unix")
+	row.Column(fmt.Sprintf("%d", t.Unix()))
+
+	row = tab.Row()
+	row.Column("Hex")
+	row.Column(fmt.Sprintf("%#x", t.Unix()))
+
+	row = tab.Row()
+	row.Column("Milliseconds")
+	row.Column(new(big.Int).Quo(ns, big.NewInt(1000000)).String())
+
+	row = tab.Row()
+	row.Column("Nanoseconds")
+	row.Column(ns.String())
+
+	tab.Print(os.Stdout)
+
 	return nil
 }
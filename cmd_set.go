@@ -0,0 +1,109 @@
+//
+// Copyright (c) 2024 Markku Rossi
+//
+// All rights reserved.
+//
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/markkurossi/calc/eval"
+	"github.com/markkurossi/tabulate"
+)
+
+func cmdSet() error {
+	if !input.HasToken() {
+		return printSettings()
+	}
+	t, err := input.GetToken()
+	if err != nil {
+		return err
+	}
+	if t.Type != eval.TIdentifier {
+		return eval.NewError(t.Column,
+			fmt.Errorf("unexpected token '%s'", t))
+	}
+	switch t.StrVal {
+	case "var", "variable":
+		return setVariable()
+
+	default:
+		// Read the value with the default configuration so that the
+		// current integer word size does not limit setting values.
+		input.SetConfig(eval.NewConfig())
+		v, err := input.GetToken()
+		input.SetConfig(env.Config())
+		if err != nil {
+			return err
+		}
+		err = input.ExpectEOL()
+		if err != nil {
+			return err
+		}
+		value := v.String()
+		if v.Type == eval.TString {
+			value = v.StrVal
+		}
+		err = env.Config().Set(t.StrVal, value)
+		if err != nil {
+			return eval.NewError(v.Column, err)
+		}
+		return nil
+	}
+}
+
+func printSettings() error {
+	tab := tabulate.New(tabulate.Simple)
+	tab.Header("Setting").SetAlign(tabulate.ML)
+	tab.Header("Value").SetAlign(tabulate.ML)
+	tab.Header("Description").SetAlign(tabulate.ML)
+
+	for _, setting := range env.Config().Settings() {
+		row := tab.Row()
+		row.Column(setting.Name)
+		row.Column(setting.Value)
+		row.Column(setting.Title)
+	}
+	tab.Print(os.Stdout)
+
+	return nil
+}
+
+func setVariable() error {
+	t, err := input.GetToken()
+	if err != nil {
+		return err
+	}
+	if t.Type != eval.TIdentifier {
+		return eval.NewError(t.Column,
+			fmt.Errorf("unexpected token '%s'", t))
+	}
+	name := t.StrVal
+
+	t, err = input.GetToken()
+	if err != nil {
+		return err
+	}
+	if t.Type != '=' {
+		return eval.NewError(t.Column,
+			fmt.Errorf("unexpected token '%s'", t))
+	}
+	expr, err := eval.NewParser(input).Parse()
+	if err != nil {
+		return err
+	}
+	err = input.ExpectEOL()
+	if err != nil {
+		return err
+	}
+	val, err := expr.Eval(env)
+	if err != nil {
+		return err
+	}
+	env.Set(name, val)
+
+	return nil
+}
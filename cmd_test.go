@@ -0,0 +1,48 @@
+//
+// Copyright (c) 2024 Markku Rossi
+//
+// All rights reserved.
+//
+
+package main
+
+import (
+	"testing"
+
+	"github.com/markkurossi/calc/eval"
+)
+
+var commandErrorTests = []struct {
+	cmd  func() error
+	line string
+	col  int
+}{
+	{cmdPrint, "1,5", 1},
+	{cmdPrint, "12,345,6", 2},
+	{cmdPrint, "1.2.3", 3},
+	{cmdPrint, "00.4 + 1", 2},
+	{cmdPrint, "/x 1 2", 5},
+	{cmdPrint, "/s 1h", 3},
+	{cmdPrint, "/c 250ms", 3},
+	{cmdSet, "var x = 1,5", 9},
+	{cmdSet, "precision 64 1", 13},
+	{cmdConvert, "1KiB to B 1", 10},
+	{cmdDefine, "f(x) = x 1", 9},
+}
+
+func TestCommandError(t *testing.T) {
+	env = eval.NewEnv()
+	for idx, test := range commandErrorTests {
+		input = eval.NewStringInput(test.line)
+		input.SetConfig(env.Config())
+		err := test.cmd()
+		if err == nil {
+			t.Errorf("test %d: '%s' did not fail", idx, test.line)
+			continue
+		}
+		if col := eval.Column(err); col != test.col {
+			t.Errorf("test %d: '%s': error column %d, expected %d: %s",
+				idx, test.line, col, test.col, err)
+		}
+	}
+}
@@ -0,0 +1,1181 @@
+//
+// Copyright (c) 2023-2024 Markku Rossi
+//
+// All rights reserved.
+//
+
+package eval
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	bin "encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"math/cmplx"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/sha3"
+)
+
+var (
+	_ Expr = &Builtin{}
+)
+
+// Builtin implements builtin functions.
+type Builtin struct {
+	name string
+	col  int
+	args []Expr
+}
+
+// BuiltinFunc defines a builtin function.
+type BuiltinFunc struct {
+	Name    string
+	Title   string
+	MinArgs int
+	MaxArgs int
+	Eval    func(bi *Builtin, env *Env) (Value, error)
+}
+
+var builtins = make(map[string]*BuiltinFunc)
+
+func init() {
+	for _, bi := range []*BuiltinFunc{
+		{
+			Name:    "abs",
+			Title:   "Return the absolute value or the magnitude of a complex number",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    builtinAbs,
+		},
+		{
+			Name:    "adler32",
+			Title:   "Compute the Adler-32 checksum of string or integer bytes",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    checksumBuiltin(newAdler32),
+		},
+		{
+			Name:    "arg",
+			Title:   "Return the phase angle of a complex number in radians",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    builtinArg,
+		},
+		{
+			Name:    "base32",
+			Title:   "Encode string or integer bytes in base32",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    encodeBuiltin(EncodingBase32),
+		},
+		{
+			Name:    "base64",
+			Title:   "Encode string or integer bytes in base64",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    encodeBuiltin(EncodingBase64),
+		},
+		{
+			Name:    "broadcast",
+			Title:   "Return the broadcast or last address of a CIDR block",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    builtinBroadcast,
+		},
+		{
+			Name:    "chr",
+			Title:   "Return the one-character string of a Unicode code point",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    builtinChr,
+		},
+		{
+			Name:    "conj",
+			Title:   "Return the complex conjugate",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    builtinConj,
+		},
+		{
+			Name:    "contains",
+			Title:   "Test if CIDR block contains address or block: contains(CIDR, X)",
+			MinArgs: 2,
+			MaxArgs: 2,
+			Eval:    builtinContains,
+		},
+		{
+			Name:    "crc",
+			Title:   "Compute CRC: crc(WIDTH, POLY, INIT, REFIN, REFOUT, XOROUT, X)",
+			MinArgs: 7,
+			MaxArgs: 7,
+			Eval:    builtinCRC,
+		},
+		{
+			Name:    "crc16",
+			Title:   "Compute the CRC-16/ARC of string or integer bytes",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    crcBuiltin(crc16),
+		},
+		{
+			Name:    "crc32",
+			Title:   "Compute the CRC-32/IEEE of string or integer bytes",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    checksumBuiltin(newCRC32),
+		},
+		{
+			Name:    "crc32c",
+			Title:   "Compute the CRC-32C (Castagnoli) of string or integer bytes",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    checksumBuiltin(newCRC32C),
+		},
+		{
+			Name:    "crc64",
+			Title:   "Compute the CRC-64/XZ of string or integer bytes",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    checksumBuiltin(newCRC64),
+		},
+		{
+			Name:    "crc8",
+			Title:   "Compute the CRC-8 of string or integer bytes",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    crcBuiltin(crc8),
+		},
+		{
+			Name:    "date",
+			Title:   "Convert Unix time or string to time: date(X [, s|ms|us|ns])",
+			MinArgs: 1,
+			MaxArgs: 2,
+			Eval:    builtinDate,
+		},
+		{
+			Name:    "den",
+			Title:   "Return the denominator of a rational number",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    builtinDen,
+		},
+		{
+			Name:    "eui64",
+			Title:   "Convert EUI-48 MAC address to modified EUI-64 identifier",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    builtinEUI64,
+		},
+		{
+			Name:    "fnv32",
+			Title:   "Compute the FNV-1 32-bit hash of string or integer bytes",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    checksumBuiltin(newFNV32),
+		},
+		{
+			Name:    "fnv32a",
+			Title:   "Compute the FNV-1a 32-bit hash of string or integer bytes",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    checksumBuiltin(newFNV32a),
+		},
+		{
+			Name:    "fnv64",
+			Title:   "Compute the FNV-1 64-bit hash of string or integer bytes",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    checksumBuiltin(newFNV64),
+		},
+		{
+			Name:    "fnv64a",
+			Title:   "Compute the FNV-1a 64-bit hash of string or integer bytes",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    checksumBuiltin(newFNV64a),
+		},
+		{
+			Name:    "fromq",
+			Title:   "Create fixed point number from raw integer: fromq(FRAC, RAW [, BITS])",
+			MinArgs: 2,
+			MaxArgs: 3,
+			Eval:    builtinFromQ,
+		},
+		{
+			Name:    "hex",
+			Title:   "Encode string or integer bytes as hexadecimal digits",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    encodeBuiltin(EncodingHex),
+		},
+		{
+			Name:    "hosts",
+			Title:   "Return the number of host addresses in a CIDR block",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    builtinHosts,
+		},
+		{
+			Name:    "imag",
+			Title:   "Return the imaginary part of a complex number",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    builtinImag,
+		},
+		{
+			Name:    "index",
+			Title:   "Return the rune index of a substring or -1: index(S, SUB)",
+			MinArgs: 2,
+			MaxArgs: 2,
+			Eval:    builtinIndex,
+		},
+		{
+			Name:    "ip",
+			Title:   "Convert integer or string to IP address: ip(X [, 4|6])",
+			MinArgs: 1,
+			MaxArgs: 2,
+			Eval:    builtinIP,
+		},
+		{
+			Name:    "len",
+			Title:   "Return the length of a string in runes",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    builtinLen,
+		},
+		{
+			Name:    "linklocal",
+			Title:   "Return the IPv6 link-local address of a MAC address",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    builtinLinkLocal,
+		},
+		{
+			Name:    "local",
+			Title:   "Test if the universal/local bit of a MAC address is set",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    builtinLocal,
+		},
+		{
+			Name:    "lower",
+			Title:   "Convert string to lower case",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    builtinLower,
+		},
+		{
+			Name:    "mac",
+			Title:   "Convert integer or string to MAC address",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    builtinMAC,
+		},
+		{
+			Name:    "md5",
+			Title:   "Return the MD5 hash of string or integer bytes as hex string",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    hashBuiltin(md5.New),
+		},
+		{
+			Name:    "mpfloat",
+			Title:   "Convert value to mpfloat",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    builtinMPFloat,
+		},
+		{
+			Name:    "multicast",
+			Title:   "Test if the individual/group bit of a MAC address is set",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    builtinMulticast,
+		},
+		{
+			Name:    "netmask",
+			Title:   "Return the network mask of a CIDR block",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    builtinNetmask,
+		},
+		{
+			Name:    "network",
+			Title:   "Return the network address of a CIDR block",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    builtinNetwork,
+		},
+		{
+			Name:    "now",
+			Title:   "Return the current time",
+			MinArgs: 0,
+			MaxArgs: 0,
+			Eval:    builtinNow,
+		},
+		{
+			Name:    "num",
+			Title:   "Return the numerator of a rational number",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    builtinNum,
+		},
+		{
+			Name:    "ord",
+			Title:   "Return the Unicode code point of a one-character string",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    builtinOrd,
+		},
+		{
+			Name:    "oui",
+			Title:   "Return the OUI of a MAC address",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    builtinOUI,
+		},
+		{
+			Name:    "pack",
+			Title:   "Pack integer to string of bytes: pack(X, SIZE, \"be\"|\"le\")",
+			MinArgs: 3,
+			MaxArgs: 3,
+			Eval:    builtinPack,
+		},
+		{
+			Name:    "q",
+			Title:   "Convert value to fixed point number: q(FRAC, X [, BITS])",
+			MinArgs: 2,
+			MaxArgs: 3,
+			Eval:    builtinQ,
+		},
+		{
+			Name:    "qp",
+			Title:   "Encode string or integer bytes in quoted-printable",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    encodeBuiltin(EncodingQP),
+		},
+		{
+			Name:    "random",
+			Title:   "Return a random int64 value",
+			MinArgs: 0,
+			MaxArgs: 1,
+			Eval:    builtinRandom,
+		},
+		{
+			Name:    "rat",
+			Title:   "Convert value to exact rational number",
+			MinArgs: 1,
+			MaxArgs: 2,
+			Eval:    builtinRat,
+		},
+		{
+			Name:    "raw",
+			Title:   "Return the raw integer of a fixed point number",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    builtinRaw,
+		},
+		{
+			Name:    "real",
+			Title:   "Return the real part of a complex number",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    builtinReal,
+		},
+		{
+			Name:    "sha1",
+			Title:   "Return the SHA-1 hash of string or integer bytes as hex string",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    hashBuiltin(sha1.New),
+		},
+		{
+			Name:    "sha224",
+			Title:   "Return the SHA-224 hash of string or integer bytes as hex string",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    hashBuiltin(sha256.New224),
+		},
+		{
+			Name:    "sha256",
+			Title:   "Return the SHA-256 hash of string or integer bytes as hex string",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    hashBuiltin(sha256.New),
+		},
+		{
+			Name:    "sha384",
+			Title:   "Return the SHA-384 hash of string or integer bytes as hex string",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    hashBuiltin(sha512.New384),
+		},
+		{
+			Name:    "sha3_224",
+			Title:   "Return the SHA3-224 hash of string or integer bytes as hex string",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    hashBuiltin(sha3.New224),
+		},
+		{
+			Name:    "sha3_256",
+			Title:   "Return the SHA3-256 hash of string or integer bytes as hex string",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    hashBuiltin(sha3.New256),
+		},
+		{
+			Name:    "sha3_384",
+			Title:   "Return the SHA3-384 hash of string or integer bytes as hex string",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    hashBuiltin(sha3.New384),
+		},
+		{
+			Name:    "sha3_512",
+			Title:   "Return the SHA3-512 hash of string or integer bytes as hex string",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    hashBuiltin(sha3.New512),
+		},
+		{
+			Name:    "sha512",
+			Title:   "Return the SHA-512 hash of string or integer bytes as hex string",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    hashBuiltin(sha512.New),
+		},
+		{
+			Name:    "subnet",
+			Title:   "Return subnet of CIDR block: subnet(CIDR, NEWBITS [, NUM])",
+			MinArgs: 2,
+			MaxArgs: 3,
+			Eval:    builtinSubnet,
+		},
+		{
+			Name:    "substr",
+			Title:   "Return substring of runes: substr(S, START [, LENGTH])",
+			MinArgs: 2,
+			MaxArgs: 3,
+			Eval:    builtinSubstr,
+		},
+		{
+			Name:    "ticks",
+			Title:   "Convert duration to clock ticks: ticks(DURATION, FREQUENCY)",
+			MinArgs: 2,
+			MaxArgs: 2,
+			Eval:    builtinTicks,
+		},
+		{
+			Name:    "unbase32",
+			Title:   "Decode base32 string",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    decodeBuiltin(EncodingBase32),
+		},
+		{
+			Name:    "unbase64",
+			Title:   "Decode standard or URL-safe base64 string",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    decodeBuiltin(EncodingBase64),
+		},
+		{
+			Name:    "unhex",
+			Title:   "Decode hexadecimal digits to string bytes",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    decodeBuiltin(EncodingHex),
+		},
+		{
+			Name:    "unix",
+			Title:   "Convert time to Unix time: unix(TIME [, s|ms|us|ns])",
+			MinArgs: 1,
+			MaxArgs: 2,
+			Eval:    builtinUnix,
+		},
+		{
+			Name:    "unpack",
+			Title:   "Unpack string of bytes to integer: unpack(S, \"be\"|\"le\")",
+			MinArgs: 2,
+			MaxArgs: 2,
+			Eval:    builtinUnpack,
+		},
+		{
+			Name:    "unqp",
+			Title:   "Decode quoted-printable string",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    decodeBuiltin(EncodingQP),
+		},
+		{
+			Name:    "unurl",
+			Title:   "Decode URL query escaped string",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    decodeBuiltin(EncodingURL),
+		},
+		{
+			Name:    "upper",
+			Title:   "Convert string to upper case",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    builtinUpper,
+		},
+		{
+			Name:    "url",
+			Title:   "Encode string or integer bytes with URL query escaping",
+			MinArgs: 1,
+			MaxArgs: 1,
+			Eval:    encodeBuiltin(EncodingURL),
+		},
+	} {
+		builtins[bi.Name] = bi
+	}
+}
+
+// Builtins returns the builtin functions sorted by their names.
+func Builtins() []*BuiltinFunc {
+	var result []*BuiltinFunc
+	for _, bi := range builtins {
+		result = append(result, bi)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// Eval implements Expr.Eval.
+func (bi *Builtin) Eval(env *Env) (Value, error) {
+	f, ok := builtins[bi.name]
+	if !ok {
+		return bi.call(env)
+	}
+	if len(bi.args) < f.MinArgs {
+		return nil, NewError(bi.col,
+			fmt.Errorf("%s: too few arguments", bi.name))
+	}
+	if len(bi.args) > f.MaxArgs {
+		return nil, NewError(bi.col,
+			fmt.Errorf("%s: too many arguments", bi.name))
+	}
+	return f.Eval(bi, env)
+}
+
+func builtinRandom(bi *Builtin, env *Env) (Value, error) {
+	var buf [8]byte
+	_, err := rand.Read(buf[:])
+	if err != nil {
+		return nil, NewError(bi.col, fmt.Errorf("%s: %s", bi.name, err))
+	}
+	return Int64Value(bin.BigEndian.Uint64(buf[:])), nil
+}
+
+// ratArg evaluates the builtin function's idx:th argument as a
+// rational number.
+func (bi *Builtin) ratArg(env *Env, idx int) (*big.Rat, error) {
+	v, err := bi.args[idx].Eval(env)
+	if err != nil {
+		return nil, err
+	}
+	r, err := ValueRat(v)
+	if err != nil {
+		return nil, NewError(bi.col, fmt.Errorf("%s: %s", bi.name, err))
+	}
+	return r, nil
+}
+
+func builtinRat(bi *Builtin, env *Env) (Value, error) {
+	r, err := bi.ratArg(env, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(bi.args) > 1 {
+		d, err := bi.ratArg(env, 1)
+		if err != nil {
+			return nil, err
+		}
+		if d.Sign() == 0 {
+			return nil, NewError(bi.col, errDivideByZero)
+		}
+		r.Quo(r, d)
+	}
+	return RationalValue{
+		r: r,
+	}, nil
+}
+
+func builtinNum(bi *Builtin, env *Env) (Value, error) {
+	r, err := bi.ratArg(env, 0)
+	if err != nil {
+		return nil, err
+	}
+	return ratInt(bi, r.Num())
+}
+
+func builtinDen(bi *Builtin, env *Env) (Value, error) {
+	r, err := bi.ratArg(env, 0)
+	if err != nil {
+		return nil, err
+	}
+	return ratInt(bi, r.Denom())
+}
+
+func ratInt(bi *Builtin, i *big.Int) (Value, error) {
+	if !i.IsInt64() {
+		return nil, NewError(bi.col,
+			fmt.Errorf("%s: integer overflow: %s", bi.name, i))
+	}
+	return Int64Value(i.Int64()), nil
+}
+
+func builtinMPFloat(bi *Builtin, env *Env) (Value, error) {
+	v, err := bi.args[0].Eval(env)
+	if err != nil {
+		return nil, err
+	}
+	f, err := env.Config().ValueBigFloat(v)
+	if err != nil {
+		r, err := ValueRat(v)
+		if err != nil {
+			return nil, NewError(bi.col, fmt.Errorf("%s: %s", bi.name, err))
+		}
+		return BigFloatValue{
+			f: env.Config().NewFloat().SetRat(r),
+		}, nil
+	}
+	return BigFloatValue{
+		f: env.Config().NewFloat().Set(f),
+	}, nil
+}
+
+func builtinReal(bi *Builtin, env *Env) (Value, error) {
+	v, err := bi.args[0].Eval(env)
+	if err != nil {
+		return nil, err
+	}
+	c, ok := v.(ComplexValue)
+	if ok {
+		return Float64Value(real(c)), nil
+	}
+	return v, nil
+}
+
+func builtinImag(bi *Builtin, env *Env) (Value, error) {
+	v, err := bi.args[0].Eval(env)
+	if err != nil {
+		return nil, err
+	}
+	c, err := ValueComplex(v)
+	if err != nil {
+		return nil, NewError(bi.col, fmt.Errorf("%s: %s", bi.name, err))
+	}
+	return Float64Value(imag(c)), nil
+}
+
+func builtinAbs(bi *Builtin, env *Env) (Value, error) {
+	v, err := bi.args[0].Eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch val := v.(type) {
+	case Int8Value:
+		if val < 0 {
+			return -val, nil
+		}
+		return val, nil
+	case Int16Value:
+		if val < 0 {
+			return -val, nil
+		}
+		return val, nil
+	case Int32Value:
+		if val < 0 {
+			return -val, nil
+		}
+		return val, nil
+	case Int64Value:
+		if val == math.MinInt64 {
+			return nil, NewError(bi.col,
+				fmt.Errorf("%s: integer overflow in %d", bi.name, val))
+		}
+		if val < 0 {
+			return -val, nil
+		}
+		return val, nil
+	case Uint8Value, Uint16Value, Uint32Value, Uint64Value, Uint128Value:
+		return val, nil
+	case Int128Value:
+		min, _ := intRange(128, true)
+		if val.i.Cmp(min) == 0 {
+			return nil, NewError(bi.col,
+				fmt.Errorf("%s: integer overflow in %s", bi.name, val))
+		}
+		return NewInt128Value(new(big.Int).Abs(val.i)), nil
+	case Float64Value:
+		return Float64Value(math.Abs(float64(val))), nil
+	case BigFloatValue:
+		return BigFloatValue{
+			f: new(big.Float).Abs(val.f),
+		}, nil
+	case RationalValue:
+		return RationalValue{
+			r: new(big.Rat).Abs(val.r),
+		}, nil
+	case ComplexValue:
+		return Float64Value(cmplx.Abs(complex128(val))), nil
+	case UnitValue:
+		return UnitValue{
+			r:    new(big.Rat).Abs(val.r),
+			unit: val.unit,
+		}, nil
+	case DurationValue:
+		return DurationValue{
+			r: new(big.Rat).Abs(val.r),
+		}, nil
+	default:
+		return nil, NewError(bi.col,
+			fmt.Errorf("%s: unsupported argument %s", bi.name, v.Type()))
+	}
+}
+
+func builtinArg(bi *Builtin, env *Env) (Value, error) {
+	v, err := bi.args[0].Eval(env)
+	if err != nil {
+		return nil, err
+	}
+	c, err := ValueComplex(v)
+	if err != nil {
+		return nil, NewError(bi.col, fmt.Errorf("%s: %s", bi.name, err))
+	}
+	return Float64Value(cmplx.Phase(c)), nil
+}
+
+func builtinConj(bi *Builtin, env *Env) (Value, error) {
+	v, err := bi.args[0].Eval(env)
+	if err != nil {
+		return nil, err
+	}
+	c, ok := v.(ComplexValue)
+	if ok {
+		return ComplexValue(cmplx.Conj(complex128(c))), nil
+	}
+	return v, nil
+}
+
+// fixedFormat evaluates the fraction bits and the optional word size
+// arguments of the fixed point builtins. The default word size is
+// the smallest word that holds the sign and fraction bits.
+func (bi *Builtin) fixedFormat(env *Env) (uint, uint, error) {
+	frac, err := bi.intArg(env, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	if frac < 0 || frac > 63 {
+		return 0, 0, NewError(bi.col,
+			fmt.Errorf("%s: invalid fraction bits %d", bi.name, frac))
+	}
+	bits := int64(fixedWordSize(uint(frac)))
+	if len(bi.args) > 2 {
+		bits, err = bi.intArg(env, 2)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	return uint(frac), uint(bits), nil
+}
+
+// intArg evaluates the builtin function's idx:th argument as an
+// integer.
+func (bi *Builtin) intArg(env *Env, idx int) (int64, error) {
+	v, err := bi.args[idx].Eval(env)
+	if err != nil {
+		return 0, err
+	}
+	i, err := ValueInt64(v)
+	if err != nil {
+		return 0, NewError(bi.col, fmt.Errorf("%s: %s", bi.name, err))
+	}
+	return i, nil
+}
+
+func builtinQ(bi *Builtin, env *Env) (Value, error) {
+	frac, bits, err := bi.fixedFormat(env)
+	if err != nil {
+		return nil, err
+	}
+	t, err := NewFixedValue(0, frac, bits)
+	if err != nil {
+		return nil, NewError(bi.col, fmt.Errorf("%s: %s", bi.name, err))
+	}
+	v, err := bi.args[1].Eval(env)
+	if err != nil {
+		return nil, err
+	}
+	f, err := toFixed(env, v, t)
+	if err != nil {
+		return nil, NewError(bi.col, fmt.Errorf("%s: %s", bi.name, err))
+	}
+	return f, nil
+}
+
+func builtinFromQ(bi *Builtin, env *Env) (Value, error) {
+	frac, bits, err := bi.fixedFormat(env)
+	if err != nil {
+		return nil, err
+	}
+	v, err := bi.args[1].Eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if len(bi.args) < 3 {
+		// The raw integer type defines the word size.
+		switch v.(type) {
+		case Int8Value:
+			bits = 8
+		case Int16Value:
+			bits = 16
+		case Int32Value:
+			bits = 32
+		}
+	}
+	raw, err := ValueInt64(v)
+	if err != nil {
+		return nil, NewError(bi.col, fmt.Errorf("%s: %s", bi.name, err))
+	}
+	f, err := NewFixedValue(raw, frac, bits)
+	if err != nil {
+		return nil, NewError(bi.col, fmt.Errorf("%s: %s", bi.name, err))
+	}
+	return f, nil
+}
+
+func builtinRaw(bi *Builtin, env *Env) (Value, error) {
+	v, err := bi.args[0].Eval(env)
+	if err != nil {
+		return nil, err
+	}
+	f, ok := v.(FixedValue)
+	if !ok {
+		return nil, NewError(bi.col,
+			fmt.Errorf("%s: not a fixed point number: %s", bi.name, v))
+	}
+	return f.Raw(), nil
+}
+
+// stringArg evaluates the builtin function's idx:th argument as a
+// string.
+func (bi *Builtin) stringArg(env *Env, idx int) (string, error) {
+	v, err := bi.args[idx].Eval(env)
+	if err != nil {
+		return "", err
+	}
+	str, ok := v.(StringValue)
+	if !ok {
+		return "", NewError(bi.col,
+			fmt.Errorf("%s: not a string: %s", bi.name, v))
+	}
+	return string(str), nil
+}
+
+// textArg evaluates the builtin function's idx:th argument as a
+// string. The string must be valid UTF-8.
+func (bi *Builtin) textArg(env *Env, idx int) (string, error) {
+	str, err := bi.stringArg(env, idx)
+	if err != nil {
+		return "", err
+	}
+	if !utf8.ValidString(str) {
+		return "", NewError(bi.col,
+			fmt.Errorf("%s: string is not valid UTF-8", bi.name))
+	}
+	return str, nil
+}
+
+// dataArg evaluates the builtin function's idx:th argument as bytes.
+func (bi *Builtin) dataArg(env *Env, idx int) ([]byte, error) {
+	v, err := bi.args[idx].Eval(env)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ValueBytes(v)
+	if err != nil {
+		return nil, NewError(bi.col, fmt.Errorf("%s: %s", bi.name, err))
+	}
+	return data, nil
+}
+
+// uint64Arg evaluates the builtin function's idx:th argument as an
+// unsigned 64-bit integer. Negative numbers are converted to their
+// two's complement bit pattern.
+func (bi *Builtin) uint64Arg(env *Env, idx int) (uint64, error) {
+	v, err := bi.args[idx].Eval(env)
+	if err != nil {
+		return 0, err
+	}
+	i, err := ValueBigInt(v)
+	if err != nil {
+		return 0, NewError(bi.col, fmt.Errorf("%s: %s", bi.name, err))
+	}
+	return wrapBigInt(i, 64, false).Uint64(), nil
+}
+
+// boolArg evaluates the builtin function's idx:th argument as a
+// boolean.
+func (bi *Builtin) boolArg(env *Env, idx int) (bool, error) {
+	v, err := bi.args[idx].Eval(env)
+	if err != nil {
+		return false, err
+	}
+	b, err := ValueBool(v)
+	if err != nil {
+		return false, NewError(bi.col, fmt.Errorf("%s: %s", bi.name, err))
+	}
+	return b, nil
+}
+
+// intResult returns the integer as a value of the configured integer
+// type. If pattern is true, the integer can also be the bit pattern
+// of a negative signed integer.
+func (bi *Builtin) intResult(env *Env, i *big.Int, pattern bool) (
+	Value, error) {
+
+	v, err := env.Config().IntValue(i, pattern)
+	if err != nil {
+		return nil, NewError(bi.col, fmt.Errorf("%s: %s", bi.name, err))
+	}
+	return v, nil
+}
+
+// byteOrder evaluates the builtin function's idx:th argument as a
+// byte order name.
+func (bi *Builtin) byteOrder(env *Env, idx int) (bin.ByteOrder, error) {
+	order, err := bi.stringArg(env, idx)
+	if err != nil {
+		return nil, err
+	}
+	switch order {
+	case "be", "big":
+		return bin.BigEndian, nil
+	case "le", "little":
+		return bin.LittleEndian, nil
+	default:
+		return nil, NewError(bi.col,
+			fmt.Errorf("%s: invalid byte order '%s', expected be or le",
+				bi.name, order))
+	}
+}
+
+func builtinLen(bi *Builtin, env *Env) (Value, error) {
+	str, err := bi.textArg(env, 0)
+	if err != nil {
+		return nil, err
+	}
+	return bi.intResult(env,
+		big.NewInt(int64(utf8.RuneCountInString(str))), false)
+}
+
+func builtinSubstr(bi *Builtin, env *Env) (Value, error) {
+	str, err := bi.textArg(env, 0)
+	if err != nil {
+		return nil, err
+	}
+	runes := []rune(str)
+	start, err := bi.intArg(env, 1)
+	if err != nil {
+		return nil, err
+	}
+	if start < 0 || start > int64(len(runes)) {
+		return nil, NewError(bi.col,
+			fmt.Errorf("%s: start %d out of range", bi.name, start))
+	}
+	end := int64(len(runes))
+	if len(bi.args) > 2 {
+		length, err := bi.intArg(env, 2)
+		if err != nil {
+			return nil, err
+		}
+		if length < 0 || length > end-start {
+			return nil, NewError(bi.col,
+				fmt.Errorf("%s: length %d out of range", bi.name, length))
+		}
+		end = start + length
+	}
+	return StringValue(runes[start:end]), nil
+}
+
+func builtinIndex(bi *Builtin, env *Env) (Value, error) {
+	str, err := bi.textArg(env, 0)
+	if err != nil {
+		return nil, err
+	}
+	sub, err := bi.textArg(env, 1)
+	if err != nil {
+		return nil, err
+	}
+	idx := strings.Index(str, sub)
+	if idx > 0 {
+		idx = utf8.RuneCountInString(str[:idx])
+	}
+	return bi.intResult(env, big.NewInt(int64(idx)), false)
+}
+
+func builtinUpper(bi *Builtin, env *Env) (Value, error) {
+	str, err := bi.stringArg(env, 0)
+	if err != nil {
+		return nil, err
+	}
+	return StringValue(strings.ToUpper(str)), nil
+}
+
+func builtinLower(bi *Builtin, env *Env) (Value, error) {
+	str, err := bi.stringArg(env, 0)
+	if err != nil {
+		return nil, err
+	}
+	return StringValue(strings.ToLower(str)), nil
+}
+
+func builtinOrd(bi *Builtin, env *Env) (Value, error) {
+	str, err := bi.stringArg(env, 0)
+	if err != nil {
+		return nil, err
+	}
+	r, size := utf8.DecodeRuneInString(str)
+	if len(str) == 0 || size != len(str) {
+		return nil, NewError(bi.col,
+			fmt.Errorf("%s: expected one-character string: %q", bi.name, str))
+	}
+	return bi.intResult(env, big.NewInt(int64(r)), false)
+}
+
+func builtinChr(bi *Builtin, env *Env) (Value, error) {
+	code, err := bi.intArg(env, 0)
+	if err != nil {
+		return nil, err
+	}
+	if code < 0 || code > unicode.MaxRune || !utf8.ValidRune(rune(code)) {
+		return nil, NewError(bi.col,
+			fmt.Errorf("%s: invalid code point %d", bi.name, code))
+	}
+	return StringValue(string(rune(code))), nil
+}
+
+// encodeBuiltin creates a builtin function that encodes strings and
+// integers with the encoding.
+func encodeBuiltin(e Encoding) func(bi *Builtin, env *Env) (Value, error) {
+	return func(bi *Builtin, env *Env) (Value, error) {
+		data, err := bi.dataArg(env, 0)
+		if err != nil {
+			return nil, err
+		}
+		return StringValue(e.Encode(data)), nil
+	}
+}
+
+// decodeBuiltin creates a builtin function that decodes strings with
+// the encoding.
+func decodeBuiltin(e Encoding) func(bi *Builtin, env *Env) (Value, error) {
+	return func(bi *Builtin, env *Env) (Value, error) {
+		str, err := bi.stringArg(env, 0)
+		if err != nil {
+			return nil, err
+		}
+		data, err := e.Decode(str)
+		if err != nil {
+			return nil, NewError(bi.col, fmt.Errorf("%s: %s", bi.name, err))
+		}
+		return StringValue(data), nil
+	}
+}
+
+func builtinPack(bi *Builtin, env *Env) (Value, error) {
+	v, err := bi.args[0].Eval(env)
+	if err != nil {
+		return nil, err
+	}
+	i, err := ValueBigInt(v)
+	if err != nil {
+		return nil, NewError(bi.col, fmt.Errorf("%s: %s", bi.name, err))
+	}
+	size, err := bi.intArg(env, 1)
+	if err != nil {
+		return nil, err
+	}
+	if size <= 0 || size > 16 {
+		return nil, NewError(bi.col,
+			fmt.Errorf("%s: invalid size %d, expected 1-16 bytes",
+				bi.name, size))
+	}
+	order, err := bi.byteOrder(env, 2)
+	if err != nil {
+		return nil, err
+	}
+	// The integer is truncated to its size bytes like a two's
+	// complement number.
+	b := wrapBigInt(i, uint(size*8), false).Bytes()
+	data := make([]byte, size)
+	copy(data[len(data)-len(b):], b)
+	if order == bin.LittleEndian {
+		reverse(data)
+	}
+	return StringValue(data), nil
+}
+
+func builtinUnpack(bi *Builtin, env *Env) (Value, error) {
+	str, err := bi.stringArg(env, 0)
+	if err != nil {
+		return nil, err
+	}
+	order, err := bi.byteOrder(env, 1)
+	if err != nil {
+		return nil, err
+	}
+	data := []byte(str)
+	if order == bin.LittleEndian {
+		reverse(data)
+	}
+	return bi.intResult(env, new(big.Int).SetBytes(data), true)
+}
+
+func reverse(data []byte) {
+	for i, j := 0, len(data)-1; i < j; i, j = i+1, j-1 {
+		data[i], data[j] = data[j], data[i]
+	}
+}
+
+// call calls the user-defined function.
+func (bi *Builtin) call(env *Env) (Value, error) {
+	f, ok := env.Function(bi.name)
+	if !ok {
+		return nil, NewError(bi.col, fmt.Errorf("unknown function: '%s'",
+			bi.name))
+	}
+	var args []Value
+	for _, arg := range bi.args {
+		v, err := arg.Eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+	v, err := f.Call(env, args)
+	if err != nil {
+		if Column(err) == 0 {
+			return nil, NewError(bi.col, err)
+		}
+		return nil, err
+	}
+	return v, nil
+}
@@ -0,0 +1,123 @@
+//
+// Copyright (c) 2024 Markku Rossi
+//
+// All rights reserved.
+//
+
+package eval
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"math/cmplx"
+	"strconv"
+	"unicode"
+)
+
+var (
+	_ Value = ComplexValue(0)
+	_ Expr  = ComplexValue(0)
+)
+
+// ComplexFormat defines how complex numbers are printed.
+type ComplexFormat int
+
+// Complex number formats.
+const (
+	ComplexRect ComplexFormat = iota
+	ComplexPolar
+)
+
+// ComplexValue implements complex128 values as Value.
+type ComplexValue complex128
+
+func (v ComplexValue) String() string {
+	return v.Format(Options{
+		Base: Base10,
+	})
+}
+
+// Format implements Value.Format().
+func (v ComplexValue) Format(options Options) string {
+	c := complex128(v)
+	if options.Complex == ComplexPolar {
+		return formatComplexPart(cmplx.Abs(c), options) + "∠" +
+			formatComplexPart(cmplx.Phase(c), options)
+	}
+	str := formatComplexPart(real(c), options)
+	im := imag(c)
+	if im >= 0 || math.IsNaN(im) {
+		str += "+"
+	}
+	return str + formatComplexPart(im, options) + "i"
+}
+
+func formatComplexPart(f float64, options Options) string {
+	return options.Locale.localize(strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+// Type implements Value.Type().
+func (v ComplexValue) Type() Type {
+	return TypeComplex
+}
+
+// Eval implements Expr.Eval().
+func (v ComplexValue) Eval(env *Env) (Value, error) {
+	return v, nil
+}
+
+// ValueComplex returns the value as complex128.
+func ValueComplex(value Value) (complex128, error) {
+	switch v := value.(type) {
+	case ComplexValue:
+		return complex128(v), nil
+	case BigFloatValue:
+		f, _ := v.f.Float64()
+		return complex(f, 0), nil
+	}
+	f, err := ValueFloat64(value)
+	if err != nil {
+		return 0, fmt.Errorf("type conversion from %T to complex128 failed",
+			value)
+	}
+	return complex(f, 0), nil
+}
+
+// readImaginary reads the imaginary suffix 'i' following a number
+// literal. If the input does not continue with the suffix,
+// readImaginary returns false and does not consume any input.
+func (in *Input) readImaginary() bool {
+	r, _, err := in.Rune(false)
+	if err != nil {
+		return false
+	}
+	if r != 'i' {
+		in.UngetRune(r)
+		return false
+	}
+	n, _, err := in.Rune(false)
+	if err == nil {
+		in.UngetRune(n)
+		if unicode.IsLetter(n) || unicode.IsDigit(n) || n == '_' {
+			in.UngetRune(r)
+			return false
+		}
+	}
+	return true
+}
+
+// imaginaryLiteral creates an imaginary number token for the decimal
+// number literal.
+func imaginaryLiteral(col int, literal string) (*Token, error) {
+	f, _, err := big.ParseFloat(literal, 10, 53, big.ToNearestEven)
+	if err != nil {
+		return nil, NewError(col, err)
+	}
+	im, _ := f.Float64()
+	return &Token{
+		Column:     col,
+		Type:       TComplex,
+		ComplexVal: ComplexValue(complex(0, im)),
+	}, nil
+}
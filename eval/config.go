@@ -0,0 +1,336 @@
+//
+// Copyright (c) 2024 Markku Rossi
+//
+// All rights reserved.
+//
+
+package eval
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config defines the settings that control how expressions are
+// parsed, evaluated, and formatted.
+type Config struct {
+	Locale *Locale
+	// Exact specifies if integer division produces exact rational
+	// numbers.
+	Exact bool
+	// Rational specifies the output format of rational numbers.
+	Rational RationalFormat
+	// Digits specifies the number of fraction digits in decimal
+	// output. The zero value selects the digits automatically.
+	Digits int
+	// Precision specifies the mantissa precision of mpfloat values in
+	// bits.
+	Precision uint
+	// Rounding specifies the rounding mode of mpfloat values and
+	// decimal output.
+	Rounding big.RoundingMode
+	// Saturate specifies if fixed point arithmetic saturates or wraps
+	// on overflow.
+	Saturate bool
+	// WordSize specifies the word size of integer values in bits.
+	WordSize uint
+	// Signed specifies if integer values are signed.
+	Signed bool
+	// Magnitude specifies if negative integers are printed in
+	// non-decimal bases as signed magnitude instead of their two's
+	// complement bit pattern.
+	Magnitude bool
+	// Location specifies the time zone of time literals and time
+	// output.
+	Location *time.Location
+}
+
+// DefaultPrecision is the default mantissa precision of mpfloat
+// values in bits.
+const DefaultPrecision = 1024
+
+// MaxPrecision is the maximum mantissa precision of mpfloat values in
+// bits.
+const MaxPrecision = 1 << 20
+
+// NewConfig creates a new configuration with the default settings.
+func NewConfig() *Config {
+	return &Config{
+		Locale:    localeC,
+		Precision: DefaultPrecision,
+		Rounding:  big.ToNearestEven,
+		Saturate:  true,
+		WordSize:  64,
+		Signed:    true,
+		Location:  time.Local,
+	}
+}
+
+// NewFloat creates a new mpfloat number with the configuration's
+// precision and rounding mode.
+func (c *Config) NewFloat() *big.Float {
+	return new(big.Float).SetPrec(c.Precision).SetMode(c.Rounding)
+}
+
+// ValueBigFloat returns the value as *big.Float. Rational numbers
+// are converted with the configuration's precision and rounding mode.
+func (c *Config) ValueBigFloat(value Value) (*big.Float, error) {
+	if v, ok := value.(RationalValue); ok {
+		return c.NewFloat().SetRat(v.r), nil
+	}
+	return ValueBigFloat(value)
+}
+
+// IntType returns the type of integer values.
+func (c *Config) IntType() Type {
+	t, err := IntType(c.WordSize, c.Signed)
+	if err != nil {
+		return TypeInt64
+	}
+	return t
+}
+
+// IntValue creates an integer value of the configured word size and
+// signedness. The integer must fit in the range of the integer
+// type. If pattern is true, signed integers can also be specified
+// with their unsigned bit pattern.
+func (c *Config) IntValue(i *big.Int, pattern bool) (Value, error) {
+	t := c.IntType()
+	bits, signed := IntBits(t)
+	min, max := intRange(bits, signed)
+	if pattern {
+		_, max = intRange(bits, false)
+	}
+	if i.Cmp(min) < 0 || i.Cmp(max) > 0 {
+		return nil, fmt.Errorf("integer %s overflows %s", i, t)
+	}
+	return NewIntValue(t, i)
+}
+
+// Setting describes a configuration setting.
+type Setting struct {
+	Name  string `json:"name"`
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// Settings returns the configuration settings and their current
+// values.
+func (c *Config) Settings() []Setting {
+	return []Setting{
+		{
+			Name:  "locale",
+			Title: "Decimal and grouping separators of numbers",
+			Value: c.Locale.String(),
+		},
+		{
+			Name:  "exact",
+			Title: "Integer division produces rational numbers",
+			Value: onOff(c.Exact),
+		},
+		{
+			Name:  "rational",
+			Title: "Rational number format: fraction, mixed, decimal",
+			Value: c.Rational.String(),
+		},
+		{
+			Name:  "digits",
+			Title: "Fraction digits in decimal output, 0 for automatic",
+			Value: strconv.Itoa(c.Digits),
+		},
+		{
+			Name:  "precision",
+			Title: "Mantissa precision of mpfloat values in bits",
+			Value: strconv.FormatUint(uint64(c.Precision), 10),
+		},
+		{
+			Name:  "rounding",
+			Title: "Rounding mode of mpfloat values and decimal output",
+			Value: c.Rounding.String(),
+		},
+		{
+			Name:  "saturate",
+			Title: "Fixed point arithmetic saturates on overflow",
+			Value: onOff(c.Saturate),
+		},
+		{
+			Name:  "wordsize",
+			Title: "Word size of integer values in bits",
+			Value: strconv.FormatUint(uint64(c.WordSize), 10),
+		},
+		{
+			Name:  "signed",
+			Title: "Integer values are signed",
+			Value: onOff(c.Signed),
+		},
+		{
+			Name:  "magnitude",
+			Title: "Negative integers in binary, octal, and hex have a sign",
+			Value: onOff(c.Magnitude),
+		},
+		{
+			Name:  "timezone",
+			Title: "Time zone of times: UTC, Local, or IANA name",
+			Value: c.Location.String(),
+		},
+	}
+}
+
+// Set sets the configuration setting name to value.
+func (c *Config) Set(name, value string) error {
+	switch name {
+	case "locale":
+		l, err := LookupLocale(value)
+		if err != nil {
+			return err
+		}
+		c.Locale = l
+		return nil
+
+	case "exact":
+		b, err := parseOnOff(value)
+		if err != nil {
+			return err
+		}
+		c.Exact = b
+		return nil
+
+	case "rational":
+		f, err := LookupRationalFormat(value)
+		if err != nil {
+			return err
+		}
+		c.Rational = f
+		return nil
+
+	case "digits":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid digits '%s'", value)
+		}
+		c.Digits = n
+		return nil
+
+	case "precision":
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil || n == 0 || n > MaxPrecision {
+			return fmt.Errorf("invalid precision '%s', expected 1-%d bits",
+				value, MaxPrecision)
+		}
+		c.Precision = uint(n)
+		return nil
+
+	case "rounding":
+		mode, err := LookupRoundingMode(value)
+		if err != nil {
+			return err
+		}
+		c.Rounding = mode
+		return nil
+
+	case "saturate":
+		b, err := parseOnOff(value)
+		if err != nil {
+			return err
+		}
+		c.Saturate = b
+		return nil
+
+	case "wordsize":
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err == nil {
+			_, err = IntType(uint(n), c.Signed)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid word size '%s', expected 8, 16, 32, 64, or 128",
+				value)
+		}
+		c.WordSize = uint(n)
+		return nil
+
+	case "signed":
+		b, err := parseOnOff(value)
+		if err != nil {
+			return err
+		}
+		c.Signed = b
+		return nil
+
+	case "magnitude":
+		b, err := parseOnOff(value)
+		if err != nil {
+			return err
+		}
+		c.Magnitude = b
+		return nil
+
+	case "timezone":
+		loc, err := time.LoadLocation(value)
+		if err != nil {
+			return fmt.Errorf("invalid time zone '%s'", value)
+		}
+		c.Location = loc
+		return nil
+
+	default:
+		return fmt.Errorf("unknown setting '%s'", name)
+	}
+}
+
+// Options returns the value output options for the configuration.
+func (c *Config) Options() Options {
+	return Options{
+		Base:      Base10,
+		Locale:    c.Locale,
+		Rational:  c.Rational,
+		Digits:    c.Digits,
+		Rounding:  c.Rounding,
+		Magnitude: c.Magnitude,
+		Location:  c.Location,
+	}
+}
+
+var roundingModes = []big.RoundingMode{
+	big.ToNearestEven,
+	big.ToNearestAway,
+	big.ToZero,
+	big.AwayFromZero,
+	big.ToNegativeInf,
+	big.ToPositiveInf,
+}
+
+// LookupRoundingMode returns the rounding mode by its name. The names
+// are matched case-insensitively.
+func LookupRoundingMode(name string) (big.RoundingMode, error) {
+	var names []string
+	for _, mode := range roundingModes {
+		if strings.EqualFold(name, mode.String()) {
+			return mode, nil
+		}
+		names = append(names, mode.String())
+	}
+	return 0, fmt.Errorf("unknown rounding mode '%s', supported modes: %s",
+		name, strings.Join(names, ", "))
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+func parseOnOff(value string) (bool, error) {
+	switch value {
+	case "on", "true", "1":
+		return true, nil
+	case "off", "false", "0":
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid boolean value '%s', expected on or off",
+			value)
+	}
+}
@@ -0,0 +1,184 @@
+//
+// Copyright (c) 2024 Markku Rossi
+//
+// All rights reserved.
+//
+
+package eval
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+var (
+	_ Value = DurationValue{}
+	_ Expr  = DurationValue{}
+)
+
+// DurationValue implements time durations as Value. The durations
+// are stored as exact numbers of seconds and they are printed in the
+// format of Go's time.Duration: 1h30m0s, 250ms, 3.5µs.
+type DurationValue struct {
+	r *big.Rat
+}
+
+// NewDurationValue creates a new duration value.
+func NewDurationValue(d time.Duration) DurationValue {
+	return DurationValue{
+		r: big.NewRat(int64(d), int64(time.Second)),
+	}
+}
+
+// newQuantity returns the value r in canonical units as a duration
+// if the unit is a time unit and as a unit value otherwise.
+func newQuantity(r *big.Rat, unit *Unit) Value {
+	if unit.Dims == dimsTime {
+		return DurationValue{
+			r: r,
+		}
+	}
+	return UnitValue{
+		r:    r,
+		unit: unit,
+	}
+}
+
+// readDuration reads the remaining components of the compound
+// duration literal, such as 1h30m, whose first component is d
+// seconds.
+func (in *Input) readDuration(col int, d *big.Rat) (*Token, error) {
+	for {
+		r, c, err := in.Rune(false)
+		if err != nil {
+			break
+		}
+		in.UngetRune(r)
+		if !isDecimalDigit(r) {
+			break
+		}
+		var literal []rune
+		for {
+			r, _, err = in.Rune(false)
+			if err != nil {
+				break
+			}
+			if !isDecimalDigit(r) && (r != '.' || !in.peekDigits(1)) {
+				in.UngetRune(r)
+				break
+			}
+			literal = append(literal, r)
+		}
+		unit := in.readSuffix()
+		if unit == nil || unit.Dims != dimsTime {
+			return nil, NewError(c,
+				fmt.Errorf("missing time unit in duration: %s",
+					string(literal)))
+		}
+		v, ok := new(big.Rat).SetString(string(literal))
+		if !ok {
+			return nil, NewError(c,
+				fmt.Errorf("invalid number literal: %s", string(literal)))
+		}
+		d.Add(d, v.Mul(v, unit.Scale))
+	}
+	return &Token{
+		Column: col,
+		Type:   TUnit,
+		UnitVal: DurationValue{
+			r: d,
+		},
+	}, nil
+}
+
+// Rat returns the duration in seconds.
+func (v DurationValue) Rat() *big.Rat {
+	return new(big.Rat).Set(v.r)
+}
+
+// Nanoseconds returns the duration in nanoseconds, truncated towards
+// zero.
+func (v DurationValue) Nanoseconds() *big.Int {
+	ns := new(big.Int).Mul(v.r.Num(), big.NewInt(int64(time.Second)))
+	return ns.Quo(ns, v.r.Denom())
+}
+
+func (v DurationValue) String() string {
+	ns := v.Nanoseconds()
+	if ns.IsInt64() {
+		return time.Duration(ns.Int64()).String()
+	}
+	var sb strings.Builder
+	if ns.Sign() < 0 {
+		sb.WriteRune('-')
+		ns.Neg(ns)
+	}
+	h, rem := new(big.Int).QuoRem(ns, big.NewInt(int64(time.Hour)),
+		new(big.Int))
+	m, rem := new(big.Int).QuoRem(rem, big.NewInt(int64(time.Minute)), rem)
+	s, frac := new(big.Int).QuoRem(rem, big.NewInt(int64(time.Second)),
+		new(big.Int))
+
+	if h.Sign() > 0 {
+		fmt.Fprintf(&sb, "%sh", h)
+	}
+	if h.Sign() > 0 || m.Sign() > 0 {
+		fmt.Fprintf(&sb, "%sm", m)
+	}
+	sb.WriteString(s.String())
+	if frac.Sign() > 0 {
+		sb.WriteRune('.')
+		sb.WriteString(strings.TrimRight(fmt.Sprintf("%09d", frac), "0"))
+	}
+	sb.WriteRune('s')
+	return sb.String()
+}
+
+// Format implements Value.Format(). In the binary, octal, and
+// hexadecimal bases, the durations are printed in nanoseconds. The
+// other formats print the durations as in Base10.
+func (v DurationValue) Format(options Options) string {
+	if options.Human {
+		f, _ := v.r.Float64()
+		return formatHumanTime(f, options)
+	}
+	switch options.Base {
+	case Base2, Base8, Base16, BaseBinary:
+		ns := new(big.Rat).Mul(v.r, nsPerSecond)
+		if ns.IsInt() && !options.String {
+			return formatInteger(ns.Num(), 64, options) + " ns"
+		}
+	}
+	return options.Locale.localize(v.String())
+}
+
+// Type implements Value.Type().
+func (v DurationValue) Type() Type {
+	return TypeDuration
+}
+
+// Eval implements Expr.Eval().
+func (v DurationValue) Eval(env *Env) (Value, error) {
+	return v, nil
+}
+
+func builtinTicks(bi *Builtin, env *Env) (Value, error) {
+	var r [2]*big.Rat
+	for idx, dims := range []Dims{dimsTime, dimsFreq} {
+		v, err := bi.args[idx].Eval(env)
+		if err != nil {
+			return nil, err
+		}
+		var unit *Unit
+		r[idx], unit, err = unitOperand(v)
+		if err == nil && unit.Dims != dims && !unit.Dims.IsZero() {
+			err = fmt.Errorf("incompatible unit %s", unit)
+		}
+		if err != nil {
+			return nil, NewError(bi.col, fmt.Errorf("%s: %s", bi.name, err))
+		}
+	}
+	return unitResult(env, r[0].Mul(r[0], r[1]), canonicalUnit(dimsNone)), nil
+}
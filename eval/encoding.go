@@ -0,0 +1,165 @@
+//
+// Copyright (c) 2024 Markku Rossi
+//
+// All rights reserved.
+//
+
+package eval
+
+import (
+	"bytes"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"mime/quotedprintable"
+	"net/url"
+	"strings"
+)
+
+// Encoding defines the binary-to-text encodings of byte values.
+type Encoding int
+
+// Binary-to-text encodings.
+const (
+	EncodingNone Encoding = iota
+	EncodingHex
+	EncodingBase64
+	EncodingBase32
+	EncodingURL
+	EncodingQP
+)
+
+var encodings = map[Encoding]string{
+	EncodingNone:   "none",
+	EncodingHex:    "hex",
+	EncodingBase64: "base64",
+	EncodingBase32: "base32",
+	EncodingURL:    "url",
+	EncodingQP:     "qp",
+}
+
+func (e Encoding) String() string {
+	name, ok := encodings[e]
+	if ok {
+		return name
+	}
+	return fmt.Sprintf("{Encoding %d}", e)
+}
+
+// LookupEncoding returns the encoding by its name.
+func LookupEncoding(name string) (Encoding, error) {
+	for e, n := range encodings {
+		if n == name && e != EncodingNone {
+			return e, nil
+		}
+	}
+	return EncodingNone,
+		fmt.Errorf("unknown encoding '%s', supported encodings: %s",
+			name, "hex, base64, base32, url, qp")
+}
+
+// Encode encodes the data with the encoding.
+func (e Encoding) Encode(data []byte) string {
+	switch e {
+	case EncodingHex:
+		return hex.EncodeToString(data)
+
+	case EncodingBase64:
+		return base64.StdEncoding.EncodeToString(data)
+
+	case EncodingBase32:
+		return base32.StdEncoding.EncodeToString(data)
+
+	case EncodingURL:
+		return url.QueryEscape(string(data))
+
+	case EncodingQP:
+		var buf bytes.Buffer
+		w := quotedprintable.NewWriter(&buf)
+		w.Write(data)
+		w.Close()
+		return buf.String()
+
+	default:
+		return string(data)
+	}
+}
+
+// Decode decodes the encoded string. The base64 decoding accepts both
+// the standard and the URL-safe alphabets, with or without padding.
+// The URL decoding decodes '+' as space as in the query strings.
+func (e Encoding) Decode(str string) ([]byte, error) {
+	switch e {
+	case EncodingHex:
+		return hex.DecodeString(str)
+
+	case EncodingBase64:
+		str = strings.TrimRight(str, "=")
+		if strings.ContainsAny(str, "-_") {
+			return base64.RawURLEncoding.DecodeString(str)
+		}
+		return base64.RawStdEncoding.DecodeString(str)
+
+	case EncodingBase32:
+		return base32.StdEncoding.WithPadding(base32.NoPadding).
+			DecodeString(strings.TrimRight(str, "="))
+
+	case EncodingURL:
+		s, err := url.QueryUnescape(str)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(s), nil
+
+	case EncodingQP:
+		return ioutil.ReadAll(quotedprintable.NewReader(
+			strings.NewReader(str)))
+
+	default:
+		return []byte(str), nil
+	}
+}
+
+// intBytes returns the integer number of the word size bits as
+// big-endian bytes. Non-negative numbers use the minimum number of
+// bytes and negative numbers use the two's complement bit pattern of
+// the word size.
+func intBytes(i *big.Int, bits uint) []byte {
+	if i.Sign() < 0 {
+		data := wrapBigInt(i, bits, false).Bytes()
+		result := make([]byte, (bits+7)/8)
+		copy(result[len(result)-len(data):], data)
+		return result
+	}
+	if i.Sign() == 0 {
+		return []byte{0}
+	}
+	return i.Bytes()
+}
+
+// ValueBytes returns the value as bytes. Strings are returned as is,
+// integers are returned as big-endian bytes, and IP and MAC addresses
+// are returned in the network byte order.
+func ValueBytes(value Value) ([]byte, error) {
+	switch v := value.(type) {
+	case StringValue:
+		return []byte(v), nil
+	case IPValue:
+		return v.Bytes(), nil
+	case MACValue:
+		return v.Bytes(), nil
+	}
+	bits, _ := IntBits(value.Type())
+	if bits == 0 {
+		return nil, fmt.Errorf("type conversion from %s to bytes failed",
+			value.Type())
+	}
+	i, err := ValueBigInt(value)
+	if err != nil {
+		return nil, err
+	}
+	return intBytes(i, bits), nil
+}
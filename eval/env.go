@@ -0,0 +1,140 @@
+//
+// Copyright (c) 2024 Markku Rossi
+//
+// All rights reserved.
+//
+
+package eval
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MaxCallDepth defines the maximum nesting of user-defined function
+// calls.
+const MaxCallDepth = 1000
+
+// Env implements an evaluation environment. Each calculator session
+// has its own environment which holds the session's variables and
+// functions. Function calls evaluate their bodies in child
+// environments which hold the call arguments.
+type Env struct {
+	parent *Env
+	depth  int
+	config *Config
+	vars   map[string]Value
+	funcs  map[string]*Function
+}
+
+// NewEnv creates a new evaluation environment with the default
+// configuration.
+func NewEnv() *Env {
+	return &Env{
+		config: NewConfig(),
+		vars:   make(map[string]Value),
+		funcs:  make(map[string]*Function),
+	}
+}
+
+// Config returns the environment's configuration.
+func (env *Env) Config() *Config {
+	return env.root().config
+}
+
+// root returns the root environment of the environment chain.
+func (env *Env) root() *Env {
+	for env.parent != nil {
+		env = env.parent
+	}
+	return env
+}
+
+// Get returns the value of the variable name.
+func (env *Env) Get(name string) (Value, bool) {
+	for e := env; e != nil; e = e.parent {
+		v, ok := e.vars[name]
+		if ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// Set sets the value of the variable name.
+func (env *Env) Set(name string, value Value) {
+	env.vars[name] = value
+}
+
+// Names returns the sorted names of the environment's variables.
+func (env *Env) Names() []string {
+	var names []string
+	for name := range env.vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Function returns the user-defined function name.
+func (env *Env) Function(name string) (*Function, bool) {
+	f, ok := env.root().funcs[name]
+	return f, ok
+}
+
+// Define defines the user-defined function. The function replaces
+// any previous definition with the same name.
+func (env *Env) Define(f *Function) error {
+	_, ok := builtins[f.Name]
+	if ok {
+		return fmt.Errorf("can't redefine builtin function '%s'", f.Name)
+	}
+	env.root().funcs[f.Name] = f
+	return nil
+}
+
+// FunctionNames returns the sorted names of the user-defined
+// functions.
+func (env *Env) FunctionNames() []string {
+	var names []string
+	for name := range env.root().funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Function implements user-defined functions.
+type Function struct {
+	Name   string
+	Params []string
+	Body   Expr
+}
+
+func (f *Function) String() string {
+	return fmt.Sprintf("%s(%s)", f.Name, strings.Join(f.Params, ", "))
+}
+
+// Call calls the function with the argument values. The function
+// body is evaluated in a child environment of the root environment
+// so it sees the global variables and its arguments.
+func (f *Function) Call(env *Env, args []Value) (Value, error) {
+	if len(args) != len(f.Params) {
+		return nil, fmt.Errorf("%s: expected %d arguments, got %d",
+			f.Name, len(f.Params), len(args))
+	}
+	if env.depth >= MaxCallDepth {
+		return nil, fmt.Errorf("%s: maximum call depth %d exceeded",
+			f.Name, MaxCallDepth)
+	}
+	call := &Env{
+		parent: env.root(),
+		depth:  env.depth + 1,
+		vars:   make(map[string]Value),
+	}
+	for idx, param := range f.Params {
+		call.vars[param] = args[idx]
+	}
+	return f.Body.Eval(call)
+}
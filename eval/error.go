@@ -4,7 +4,7 @@
 // All rights reserved.
 //
 
-package main
+package eval
 
 // Error implements error with input location information.
 type Error struct {
@@ -0,0 +1,1661 @@
+//
+// Copyright (c) 2020-2024 Markku Rossi
+//
+// All rights reserved.
+//
+
+// Package eval implements the calculator expression language. It
+// parses expressions into Expr values which are evaluated in an
+// explicit environment Env.
+package eval
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+var (
+	_ Expr = BoolValue(false)
+	_ Expr = Int8Value(0)
+	_ Expr = Int16Value(0)
+	_ Expr = Int32Value(0)
+	_ Expr = Int64Value(0)
+	_ Expr = Float64Value(0)
+	_ Expr = &binary{}
+	_ Expr = &unary{}
+	_ Expr = &variable{}
+	_ Expr = &logical{}
+	_ Expr = &conditional{}
+)
+
+var errDivideByZero = errors.New("integer divide by zero")
+
+// Expr implements an expression.
+type Expr interface {
+	Eval(env *Env) (Value, error)
+}
+
+// Parser implements expression parser.
+type Parser struct {
+	in *Input
+}
+
+// NewParser creates a new parser reading tokens from the input.
+func NewParser(in *Input) *Parser {
+	return &Parser{
+		in: in,
+	}
+}
+
+// Parse parses the expression from the input string with the default
+// configuration. It returns an error if the input has tokens after
+// the expression.
+func Parse(input string) (Expr, error) {
+	return ParseConfig(input, nil)
+}
+
+// ParseConfig parses the expression from the input string with the
+// configuration. If the config is nil, the default configuration is
+// used. It returns an error if the input has tokens after the
+// expression.
+func ParseConfig(input string, config *Config) (Expr, error) {
+	in := NewStringInput(input)
+	if config != nil {
+		in.SetConfig(config)
+	}
+	expr, err := NewParser(in).Parse()
+	if err != nil {
+		return nil, err
+	}
+	err = in.ExpectEOL()
+	if err != nil {
+		return nil, err
+	}
+	return expr, nil
+}
+
+// Parse parses an expression from the parser's input. If the input is
+// interactive, the expression can continue over multiple lines in
+// which case the input prompts the user for more lines.
+func (p *Parser) Parse() (Expr, error) {
+	return p.parseExpr()
+}
+
+// ParseDefinition parses a user-defined function definition of the
+// form NAME(PARAM, ...) = EXPRESSION.
+func (p *Parser) ParseDefinition() (*Function, error) {
+	t, err := p.in.GetToken()
+	if err != nil {
+		return nil, err
+	}
+	if t.Type != TIdentifier {
+		return nil, NewError(t.Column, fmt.Errorf("unexpected token '%s'", t))
+	}
+	f := &Function{
+		Name: t.StrVal,
+	}
+	t, err = p.in.GetToken()
+	if err != nil {
+		return nil, err
+	}
+	if t.Type != '(' {
+		return nil, NewError(t.Column, fmt.Errorf("unexpected token '%s'", t))
+	}
+	seen := make(map[string]bool)
+	for {
+		t, err = p.in.GetToken()
+		if err != nil {
+			return nil, err
+		}
+		if t.Type == ')' && len(f.Params) == 0 {
+			break
+		}
+		if t.Type != TIdentifier {
+			return nil,
+				NewError(t.Column, fmt.Errorf("unexpected token '%s'", t))
+		}
+		if seen[t.StrVal] {
+			return nil, NewError(t.Column,
+				fmt.Errorf("duplicate parameter '%s'", t.StrVal))
+		}
+		seen[t.StrVal] = true
+		f.Params = append(f.Params, t.StrVal)
+
+		t, err = p.in.GetToken()
+		if err != nil {
+			return nil, err
+		}
+		if t.Type == ')' {
+			break
+		}
+		if t.Type != ',' {
+			return nil,
+				NewError(t.Column, fmt.Errorf("unexpected token '%s'", t))
+		}
+	}
+	t, err = p.in.GetToken()
+	if err != nil {
+		return nil, err
+	}
+	if t.Type != '=' {
+		return nil, NewError(t.Column, fmt.Errorf("unexpected token '%s'", t))
+	}
+	f.Body, err = p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (p *Parser) parseExpr() (Expr, error) {
+	return p.parseConditional()
+}
+
+func (p *Parser) parseConditional() (Expr, error) {
+	cond, err := p.parseLogicalOR()
+	if err != nil {
+		return nil, err
+	}
+	if !p.in.HasToken() {
+		return cond, nil
+	}
+	t, err := p.in.GetToken()
+	if err != nil {
+		return nil, err
+	}
+	if t.Type != '?' {
+		p.in.UngetToken(t)
+		return cond, nil
+	}
+	col := t.Column
+	t1, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	t, err = p.in.GetToken()
+	if err != nil {
+		return nil, err
+	}
+	if t.Type != ':' {
+		return nil, NewError(t.Column, fmt.Errorf("unexpected token '%s'", t))
+	}
+	t2, err := p.parseConditional()
+	if err != nil {
+		return nil, err
+	}
+	return &conditional{
+		col:     col,
+		cond:    cond,
+		ifTrue:  t1,
+		ifFalse: t2,
+	}, nil
+}
+
+func (p *Parser) parseLogicalOR() (Expr, error) {
+	left, err := p.parseLogicalAND()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if !p.in.HasToken() {
+			return left, nil
+		}
+		t, err := p.in.GetToken()
+		if err != nil {
+			return nil, err
+		}
+		if t.Type != TOr {
+			p.in.UngetToken(t)
+			return left, nil
+		}
+		right, err := p.parseLogicalAND()
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{
+			op:    t.Type,
+			col:   t.Column,
+			left:  left,
+			right: right,
+		}
+	}
+}
+
+func (p *Parser) parseLogicalAND() (Expr, error) {
+	left, err := p.parseBitwiseOR()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if !p.in.HasToken() {
+			return left, nil
+		}
+		t, err := p.in.GetToken()
+		if err != nil {
+			return nil, err
+		}
+		if t.Type != TAnd {
+			p.in.UngetToken(t)
+			return left, nil
+		}
+		right, err := p.parseBitwiseOR()
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{
+			op:    t.Type,
+			col:   t.Column,
+			left:  left,
+			right: right,
+		}
+	}
+}
+
+func (p *Parser) parseBitwiseOR() (Expr, error) {
+	left, err := p.parseBitwiseXOR()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if !p.in.HasToken() {
+			return left, nil
+		}
+		t, err := p.in.GetToken()
+		if err != nil {
+			return nil, err
+		}
+		if t.Type != '|' {
+			p.in.UngetToken(t)
+			return left, nil
+		}
+		right, err := p.parseBitwiseXOR()
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{
+			op:    t.Type,
+			col:   t.Column,
+			left:  left,
+			right: right,
+		}
+	}
+}
+
+func (p *Parser) parseBitwiseXOR() (Expr, error) {
+	left, err := p.parseBitwiseAND()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if !p.in.HasToken() {
+			return left, nil
+		}
+		t, err := p.in.GetToken()
+		if err != nil {
+			return nil, err
+		}
+		if t.Type != '^' {
+			p.in.UngetToken(t)
+			return left, nil
+		}
+		right, err := p.parseBitwiseAND()
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{
+			op:    t.Type,
+			col:   t.Column,
+			left:  left,
+			right: right,
+		}
+	}
+}
+
+func (p *Parser) parseBitwiseAND() (Expr, error) {
+	left, err := p.parseEquality()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if !p.in.HasToken() {
+			return left, nil
+		}
+		t, err := p.in.GetToken()
+		if err != nil {
+			return nil, err
+		}
+		if t.Type != '&' {
+			p.in.UngetToken(t)
+			return left, nil
+		}
+		right, err := p.parseEquality()
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{
+			op:    t.Type,
+			col:   t.Column,
+			left:  left,
+			right: right,
+		}
+	}
+}
+
+func (p *Parser) parseEquality() (Expr, error) {
+	left, err := p.parseRelational()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if !p.in.HasToken() {
+			return left, nil
+		}
+		t, err := p.in.GetToken()
+		if err != nil {
+			return nil, err
+		}
+		switch t.Type {
+		case TEq, TNeq:
+
+		default:
+			p.in.UngetToken(t)
+			return left, nil
+		}
+		right, err := p.parseRelational()
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{
+			op:    t.Type,
+			col:   t.Column,
+			left:  left,
+			right: right,
+		}
+	}
+}
+
+func (p *Parser) parseRelational() (Expr, error) {
+	left, err := p.parseShift()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if !p.in.HasToken() {
+			return left, nil
+		}
+		t, err := p.in.GetToken()
+		if err != nil {
+			return nil, err
+		}
+		switch t.Type {
+		case '<', '>', TLe, TGe:
+
+		default:
+			p.in.UngetToken(t)
+			return left, nil
+		}
+		right, err := p.parseShift()
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{
+			op:    t.Type,
+			col:   t.Column,
+			left:  left,
+			right: right,
+		}
+	}
+}
+
+func (p *Parser) parseShift() (Expr, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	if !p.in.HasToken() {
+		return left, nil
+	}
+	t, err := p.in.GetToken()
+	if err != nil {
+		return nil, err
+	}
+	switch t.Type {
+	case TLeftShift, TRightShift:
+
+	default:
+		p.in.UngetToken(t)
+		return left, nil
+	}
+	right, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	return &binary{
+		op:    t.Type,
+		col:   t.Column,
+		left:  left,
+		right: right,
+	}, nil
+}
+
+func (p *Parser) parseAdditive() (Expr, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if !p.in.HasToken() {
+			return left, nil
+		}
+		t, err := p.in.GetToken()
+		if err != nil {
+			return nil, err
+		}
+		switch t.Type {
+		case '+', '-':
+
+		default:
+			p.in.UngetToken(t)
+			return left, nil
+		}
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{
+			op:    t.Type,
+			col:   t.Column,
+			left:  left,
+			right: right,
+		}
+	}
+}
+
+func (p *Parser) parseMultiplicative() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if !p.in.HasToken() {
+			return left, nil
+		}
+		t, err := p.in.GetToken()
+		if err != nil {
+			return nil, err
+		}
+		switch t.Type {
+		case '*', '/', '%':
+
+		default:
+			p.in.UngetToken(t)
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{
+			op:    t.Type,
+			col:   t.Column,
+			left:  left,
+			right: right,
+		}
+	}
+}
+
+func (p *Parser) parseUnary() (Expr, error) {
+	t, err := p.in.GetToken()
+	if err != nil {
+		return nil, err
+	}
+	switch t.Type {
+	case '-', '!', '~':
+		if t.Type == '-' {
+			v, err := p.parseNegIntLiteral()
+			if v != nil || err != nil {
+				return v, err
+			}
+		}
+		expr, err := p.parsePower()
+		if err != nil {
+			return nil, err
+		}
+		return &unary{
+			op:    t.Type,
+			col:   t.Column,
+			value: expr,
+		}, nil
+
+	default:
+		p.in.UngetToken(t)
+		return p.parsePower()
+	}
+}
+
+// parseNegIntLiteral parses the negative integer literal whose
+// magnitude overflows the integer type: -128 in 8-bit words. The
+// function returns nil if the unary minus is not followed by such a
+// literal.
+func (p *Parser) parseNegIntLiteral() (Expr, error) {
+	if !p.in.HasToken() {
+		return nil, nil
+	}
+	t, err := p.in.GetToken()
+	if err != nil {
+		return nil, err
+	}
+	if t.Type != TInteger || t.IntVal != nil {
+		p.in.UngetToken(t)
+		return nil, nil
+	}
+	if p.in.HasToken() {
+		n, err := p.in.GetToken()
+		if err != nil {
+			return nil, err
+		}
+		p.in.UngetToken(n)
+		if n.Type == TPower {
+			return nil, p.intOverflow(t)
+		}
+	}
+	return t.NegIntVal, nil
+}
+
+// intOverflow returns the overflow error for the integer literal
+// which fits in the integer type only as a negative number.
+func (p *Parser) intOverflow(t *Token) error {
+	return NewError(t.Column, fmt.Errorf("integer %s overflows %s",
+		t.StrVal, p.in.config.IntType()))
+}
+
+func (p *Parser) parsePower() (Expr, error) {
+	left, err := p.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+	if !p.in.HasToken() {
+		return left, nil
+	}
+	t, err := p.in.GetToken()
+	if err != nil {
+		return nil, err
+	}
+	if t.Type != TPower {
+		p.in.UngetToken(t)
+		return left, nil
+	}
+	// The power operator is right associative and it binds tighter
+	// than the unary operator on its left: -2**2 = -(2**2) and
+	// 2**-1 = 2**(-1).
+	right, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return &binary{
+		op:    t.Type,
+		col:   t.Column,
+		left:  left,
+		right: right,
+	}, nil
+}
+
+func (p *Parser) parsePostfix() (Expr, error) {
+	t, err := p.in.GetToken()
+	if err != nil {
+		return nil, err
+	}
+	switch t.Type {
+	case '(':
+		expr, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		t, err = p.in.GetToken()
+		if err != nil {
+			return nil, err
+		}
+		if t.Type != ')' {
+			return nil,
+				NewError(t.Column, fmt.Errorf("unexpected token '%s'", t))
+		}
+		return expr, nil
+
+	case TInteger:
+		if t.IntVal == nil {
+			return nil, p.intOverflow(t)
+		}
+		return t.IntVal, nil
+
+	case TFloat:
+		return t.FloatVal, nil
+
+	case TUnit:
+		return t.UnitVal, nil
+
+	case TComplex:
+		return t.ComplexVal, nil
+
+	case TString:
+		return StringValue(t.StrVal), nil
+
+	case TAddress:
+		return t.AddrVal, nil
+
+	case TTime:
+		return t.TimeVal, nil
+
+	case TIdentifier:
+		if p.in.HasToken() {
+			n, err := p.in.GetToken()
+			if err != nil {
+				return nil, err
+			}
+			p.in.UngetToken(n)
+			if n.Type == '(' {
+				return p.parseFunction(t.StrVal, t.Column)
+			}
+		}
+		return &variable{
+			name: t.StrVal,
+			col:  t.Column,
+		}, nil
+
+	default:
+		p.in.UngetToken(t)
+		return nil, NewError(t.Column, fmt.Errorf("unexpected token '%s'", t))
+	}
+}
+
+func (p *Parser) parseFunction(name string, col int) (Expr, error) {
+	t, err := p.in.GetToken()
+	if err != nil {
+		return nil, err
+	}
+	if t.Type != '(' {
+		return nil, NewError(t.Column, fmt.Errorf("unexpected token '%s'", t))
+	}
+	t, err = p.in.GetToken()
+	if err != nil {
+		return nil, err
+	}
+	var args []Expr
+	if t.Type != ')' {
+		p.in.UngetToken(t)
+		for {
+			arg, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			t, err = p.in.GetToken()
+			if err != nil {
+				return nil, err
+			}
+			if t.Type == ')' {
+				break
+			}
+			if t.Type != ',' {
+				return nil,
+					NewError(t.Column, fmt.Errorf("unexpected token '%s'", t))
+			}
+		}
+	}
+	return &Builtin{
+		name: name,
+		col:  col,
+		args: args,
+	}, nil
+}
+
+type binary struct {
+	op    TokenType
+	col   int
+	left  Expr
+	right Expr
+}
+
+func (b binary) String() string {
+	return fmt.Sprintf("%s %s %s", b.left, b.op, b.right)
+}
+
+func (b binary) Eval(env *Env) (Value, error) {
+	v1, err := b.left.Eval(env)
+	if err != nil {
+		return nil, err
+	}
+	v2, err := b.right.Eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if v1.Type() == TypeString || v2.Type() == TypeString {
+		return b.evalString(v1, v2)
+	}
+	if isIPType(v1.Type()) || isIPType(v2.Type()) {
+		return b.evalIP(env, v1, v2)
+	}
+	if v1.Type() == TypeMAC || v2.Type() == TypeMAC {
+		return b.evalMAC(env, v1, v2)
+	}
+	if v1.Type() == TypeTime || v2.Type() == TypeTime {
+		return b.evalTime(env, v1, v2)
+	}
+	if v1.Type() == TypeUnit || v2.Type() == TypeUnit ||
+		v1.Type() == TypeDuration || v2.Type() == TypeDuration {
+		return b.evalUnit(env, v1, v2)
+	}
+	if v1.Type() == TypeFixed || v2.Type() == TypeFixed {
+		return b.evalFixed(env, v1, v2)
+	}
+	t, err := ConversionType(v1, v2)
+	if err != nil {
+		return nil, err
+	}
+
+	switch b.op {
+	case TEq, TNeq, '<', '>', TLe, TGe:
+		return b.compare(env, t, v1, v2)
+	}
+	if b.op == '/' && t > TypeBool && t < TypeRational && env.Config().Exact {
+		t = TypeRational
+	}
+
+	switch t {
+	case TypeInt8:
+		i1, err := ValueInt8(v1)
+		if err != nil {
+			return nil, err
+		}
+		i2, err := ValueInt8(v2)
+		if err != nil {
+			return nil, err
+		}
+		var result int8
+		switch b.op {
+		case '/':
+			if i2 == 0 {
+				return nil, NewError(b.col, errDivideByZero)
+			}
+			result = i1 / i2
+		case '*':
+			result = i1 * i2
+		case '%':
+			if i2 == 0 {
+				return nil, NewError(b.col, errDivideByZero)
+			}
+			result = i1 % i2
+		case '+':
+			result = i1 + i2
+		case '-':
+			result = i1 - i2
+		case TLeftShift, TRightShift:
+			if i2 < 0 {
+				return nil, NewError(b.col,
+					fmt.Errorf("negative shift count %d", i2))
+			}
+			if b.op == TLeftShift {
+				result = i1 << i2
+			} else {
+				result = i1 >> i2
+			}
+		case '&':
+			result = i1 & i2
+		case '|':
+			result = i1 | i2
+		case '^':
+			result = i1 ^ i2
+		case TPower:
+			r, err := intPow(int64(i1), int64(i2), 8)
+			if err != nil {
+				return nil, NewError(b.col, err)
+			}
+			result = int8(r)
+		default:
+			return nil,
+				NewError(b.col, fmt.Errorf("unsupport binary operand '%s'",
+					b.op))
+		}
+		return Int8Value(result), nil
+
+	case TypeInt16:
+		i1, err := ValueInt16(v1)
+		if err != nil {
+			return nil, err
+		}
+		i2, err := ValueInt16(v2)
+		if err != nil {
+			return nil, err
+		}
+		var result int16
+		switch b.op {
+		case '/':
+			if i2 == 0 {
+				return nil, NewError(b.col, errDivideByZero)
+			}
+			result = i1 / i2
+		case '*':
+			result = i1 * i2
+		case '%':
+			if i2 == 0 {
+				return nil, NewError(b.col, errDivideByZero)
+			}
+			result = i1 % i2
+		case '+':
+			result = i1 + i2
+		case '-':
+			result = i1 - i2
+		case TLeftShift, TRightShift:
+			if i2 < 0 {
+				return nil, NewError(b.col,
+					fmt.Errorf("negative shift count %d", i2))
+			}
+			if b.op == TLeftShift {
+				result = i1 << i2
+			} else {
+				result = i1 >> i2
+			}
+		case '&':
+			result = i1 & i2
+		case '|':
+			result = i1 | i2
+		case '^':
+			result = i1 ^ i2
+		case TPower:
+			r, err := intPow(int64(i1), int64(i2), 16)
+			if err != nil {
+				return nil, NewError(b.col, err)
+			}
+			result = int16(r)
+		default:
+			return nil,
+				NewError(b.col, fmt.Errorf("unsupport binary operand '%s'",
+					b.op))
+		}
+		return Int16Value(result), nil
+
+	case TypeInt32:
+		i1, err := ValueInt32(v1)
+		if err != nil {
+			return nil, err
+		}
+		i2, err := ValueInt32(v2)
+		if err != nil {
+			return nil, err
+		}
+		var result int32
+		switch b.op {
+		case '/':
+			if i2 == 0 {
+				return nil, NewError(b.col, errDivideByZero)
+			}
+			result = i1 / i2
+		case '*':
+			result = i1 * i2
+		case '%':
+			if i2 == 0 {
+				return nil, NewError(b.col, errDivideByZero)
+			}
+			result = i1 % i2
+		case '+':
+			result = i1 + i2
+		case '-':
+			result = i1 - i2
+		case TLeftShift, TRightShift:
+			if i2 < 0 {
+				return nil, NewError(b.col,
+					fmt.Errorf("negative shift count %d", i2))
+			}
+			if b.op == TLeftShift {
+				result = i1 << i2
+			} else {
+				result = i1 >> i2
+			}
+		case '&':
+			result = i1 & i2
+		case '|':
+			result = i1 | i2
+		case '^':
+			result = i1 ^ i2
+		case TPower:
+			r, err := intPow(int64(i1), int64(i2), 32)
+			if err != nil {
+				return nil, NewError(b.col, err)
+			}
+			result = int32(r)
+		default:
+			return nil,
+				NewError(b.col, fmt.Errorf("unsupport binary operand '%s'",
+					b.op))
+		}
+		return Int32Value(result), nil
+
+	case TypeInt64:
+		i1, err := ValueInt64(v1)
+		if err != nil {
+			return nil, err
+		}
+		i2, err := ValueInt64(v2)
+		if err != nil {
+			return nil, err
+		}
+		var result int64
+		switch b.op {
+		case '/':
+			if i2 == 0 {
+				return nil, NewError(b.col, errDivideByZero)
+			}
+			result = i1 / i2
+		case '*':
+			result = i1 * i2
+		case '%':
+			if i2 == 0 {
+				return nil, NewError(b.col, errDivideByZero)
+			}
+			result = i1 % i2
+		case '+':
+			result = i1 + i2
+		case '-':
+			result = i1 - i2
+		case TLeftShift, TRightShift:
+			if i2 < 0 {
+				return nil, NewError(b.col,
+					fmt.Errorf("negative shift count %d", i2))
+			}
+			if b.op == TLeftShift {
+				result = i1 << i2
+			} else {
+				result = i1 >> i2
+			}
+		case '&':
+			result = i1 & i2
+		case '|':
+			result = i1 | i2
+		case '^':
+			result = i1 ^ i2
+		case TPower:
+			r, err := intPow(int64(i1), int64(i2), 64)
+			if err != nil {
+				return nil, NewError(b.col, err)
+			}
+			result = int64(r)
+		default:
+			return nil,
+				NewError(b.col, fmt.Errorf("unsupport binary operand '%s'",
+					b.op))
+		}
+		return Int64Value(result), nil
+
+	case TypeUint8, TypeUint16, TypeUint32, TypeUint64, TypeInt128,
+		TypeUint128:
+		return b.evalInt(t, v1, v2)
+
+	case TypeRational:
+		r1, err := ValueRat(v1)
+		if err != nil {
+			return nil, err
+		}
+		r2, err := ValueRat(v2)
+		if err != nil {
+			return nil, err
+		}
+		result := new(big.Rat)
+		switch b.op {
+		case '/':
+			if r2.Sign() == 0 {
+				return nil, NewError(b.col, errDivideByZero)
+			}
+			result = result.Quo(r1, r2)
+		case '*':
+			result = result.Mul(r1, r2)
+		case '%':
+			if r2.Sign() == 0 {
+				return nil, NewError(b.col, errDivideByZero)
+			}
+			result = ratMod(r1, r2)
+		case '+':
+			result = result.Add(r1, r2)
+		case '-':
+			result = result.Sub(r1, r2)
+		case TPower:
+			if !r2.IsInt() || !r2.Num().IsInt64() {
+				// Fractional exponents are not exact.
+				f1, _ := env.Config().ValueBigFloat(v1)
+				f2, _ := env.Config().ValueBigFloat(v2)
+				f, err := bigFloatPow(f1, f2, env.Config().Precision)
+				if err != nil {
+					return nil, NewError(b.col, err)
+				}
+				return BigFloatValue{
+					f: f,
+				}, nil
+			}
+			result, err = ratPow(r1, r2.Num().Int64())
+			if err != nil {
+				return nil, NewError(b.col, err)
+			}
+		default:
+			return nil,
+				NewError(b.col, fmt.Errorf("unsupport binary operand '%s'",
+					b.op))
+		}
+		return RationalValue{
+			r: result,
+		}, nil
+
+	case TypeFloat64:
+		i1, err := ValueFloat64(v1)
+		if err != nil {
+			return nil, err
+		}
+		i2, err := ValueFloat64(v2)
+		if err != nil {
+			return nil, err
+		}
+		var result float64
+		switch b.op {
+		case '/':
+			result = i1 / i2
+		case '*':
+			result = i1 * i2
+		case '+':
+			result = i1 + i2
+		case '-':
+			result = i1 - i2
+		case TPower:
+			result = math.Pow(i1, i2)
+		default:
+			return nil,
+				NewError(b.col, fmt.Errorf("unsupport binary operand '%s'",
+					b.op))
+		}
+		return Float64Value(result), nil
+
+	case TypeBigFloat:
+		i1, err := env.Config().ValueBigFloat(v1)
+		if err != nil {
+			return nil, err
+		}
+		i2, err := env.Config().ValueBigFloat(v2)
+		if err != nil {
+			return nil, err
+		}
+		result := env.Config().NewFloat()
+		switch b.op {
+		case '/':
+			result = result.Quo(i1, i2)
+		case '*':
+			result = result.Mul(i1, i2)
+		case '+':
+			result = result.Add(i1, i2)
+		case '-':
+			result = result.Sub(i1, i2)
+		case TPower:
+			result, err = bigFloatPow(i1, i2, result.Prec())
+			if err != nil {
+				return nil, NewError(b.col, err)
+			}
+		default:
+			return nil,
+				NewError(b.col, fmt.Errorf("unsupport binary operand '%s'",
+					b.op))
+		}
+		return BigFloatValue{
+			f: result,
+		}, nil
+
+	case TypeComplex:
+		c1, err := ValueComplex(v1)
+		if err != nil {
+			return nil, err
+		}
+		c2, err := ValueComplex(v2)
+		if err != nil {
+			return nil, err
+		}
+		var result complex128
+		switch b.op {
+		case '/':
+			result = c1 / c2
+		case '*':
+			result = c1 * c2
+		case '+':
+			result = c1 + c2
+		case '-':
+			result = c1 - c2
+		case TPower:
+			result = complexPow(c1, c2)
+		default:
+			return nil,
+				NewError(b.col, fmt.Errorf("unsupport binary operand '%s'",
+					b.op))
+		}
+		return ComplexValue(result), nil
+
+	default:
+		return nil,
+			NewError(b.col,
+				fmt.Errorf("unsupport values %s and %s for binary operand '%s'",
+					v1, v2, b.op))
+	}
+}
+
+// evalInt evaluates the binary operation for the integer type t with
+// arbitrary precision integers. The result wraps around at the word
+// size of the type.
+func (b binary) evalInt(t Type, v1, v2 Value) (Value, error) {
+	i1, err := ValueBigInt(v1)
+	if err != nil {
+		return nil, err
+	}
+	i2, err := ValueBigInt(v2)
+	if err != nil {
+		return nil, err
+	}
+	bits, signed := IntBits(t)
+	result := new(big.Int)
+	switch b.op {
+	case '/':
+		if i2.Sign() == 0 {
+			return nil, NewError(b.col, errDivideByZero)
+		}
+		result.Quo(i1, i2)
+	case '*':
+		result.Mul(i1, i2)
+	case '%':
+		if i2.Sign() == 0 {
+			return nil, NewError(b.col, errDivideByZero)
+		}
+		result.Rem(i1, i2)
+	case '+':
+		result.Add(i1, i2)
+	case '-':
+		result.Sub(i1, i2)
+	case TLeftShift, TRightShift:
+		if i2.Sign() < 0 {
+			return nil, NewError(b.col,
+				fmt.Errorf("negative shift count %s", i2))
+		}
+		count := bits
+		if i2.Cmp(big.NewInt(int64(bits))) < 0 {
+			count = uint(i2.Uint64())
+		}
+		if b.op == TLeftShift {
+			result.Lsh(i1, count)
+		} else {
+			result.Rsh(i1, count)
+		}
+	case '&':
+		result.And(i1, i2)
+	case '|':
+		result.Or(i1, i2)
+	case '^':
+		result.Xor(i1, i2)
+	case TPower:
+		result, err = bigIntPow(i1, i2, bits, signed)
+		if err != nil {
+			return nil, NewError(b.col, err)
+		}
+	default:
+		return nil,
+			NewError(b.col, fmt.Errorf("unsupport binary operand '%s'",
+				b.op))
+	}
+	return NewIntValue(t, result)
+}
+
+func (b binary) compare(env *Env, t Type, v1, v2 Value) (Value, error) {
+	var cmp int
+
+	switch t {
+	case TypeBool:
+		b1, err := ValueBool(v1)
+		if err != nil {
+			return nil, err
+		}
+		b2, err := ValueBool(v2)
+		if err != nil {
+			return nil, err
+		}
+		switch b.op {
+		case TEq:
+			return BoolValue(b1 == b2), nil
+		case TNeq:
+			return BoolValue(b1 != b2), nil
+		default:
+			return nil,
+				NewError(b.col, fmt.Errorf("unsupport binary operand '%s'",
+					b.op))
+		}
+
+	case TypeInt8, TypeInt16, TypeInt32, TypeInt64:
+		i1, err := ValueInt64(v1)
+		if err != nil {
+			return nil, err
+		}
+		i2, err := ValueInt64(v2)
+		if err != nil {
+			return nil, err
+		}
+		if i1 < i2 {
+			cmp = -1
+		} else if i1 > i2 {
+			cmp = 1
+		}
+
+	case TypeUint8, TypeUint16, TypeUint32, TypeUint64, TypeInt128,
+		TypeUint128:
+		i1, err := ValueBigInt(v1)
+		if err != nil {
+			return nil, err
+		}
+		i2, err := ValueBigInt(v2)
+		if err != nil {
+			return nil, err
+		}
+		cmp = i1.Cmp(i2)
+
+	case TypeFloat64:
+		f1, err := ValueFloat64(v1)
+		if err != nil {
+			return nil, err
+		}
+		f2, err := ValueFloat64(v2)
+		if err != nil {
+			return nil, err
+		}
+		if f1 != f1 || f2 != f2 {
+			// NaN compares unequal to everything.
+			return BoolValue(b.op == TNeq), nil
+		}
+		if f1 < f2 {
+			cmp = -1
+		} else if f1 > f2 {
+			cmp = 1
+		}
+
+	case TypeRational:
+		r1, err := ValueRat(v1)
+		if err != nil {
+			return nil, err
+		}
+		r2, err := ValueRat(v2)
+		if err != nil {
+			return nil, err
+		}
+		cmp = r1.Cmp(r2)
+
+	case TypeBigFloat:
+		f1, err := env.Config().ValueBigFloat(v1)
+		if err != nil {
+			return nil, err
+		}
+		f2, err := env.Config().ValueBigFloat(v2)
+		if err != nil {
+			return nil, err
+		}
+		cmp = f1.Cmp(f2)
+
+	case TypeComplex:
+		c1, err := ValueComplex(v1)
+		if err != nil {
+			return nil, err
+		}
+		c2, err := ValueComplex(v2)
+		if err != nil {
+			return nil, err
+		}
+		switch b.op {
+		case TEq:
+			return BoolValue(c1 == c2), nil
+		case TNeq:
+			return BoolValue(c1 != c2), nil
+		default:
+			return nil,
+				NewError(b.col, fmt.Errorf("unsupport binary operand '%s'",
+					b.op))
+		}
+
+	default:
+		return nil,
+			NewError(b.col,
+				fmt.Errorf("unsupport values %s and %s for binary operand '%s'",
+					v1, v2, b.op))
+	}
+	return b.compareResult(cmp), nil
+}
+
+// compareResult returns the result of the comparison operation for
+// the comparison result cmp of the operands.
+func (b binary) compareResult(cmp int) Value {
+	switch b.op {
+	case TEq:
+		return BoolValue(cmp == 0)
+	case TNeq:
+		return BoolValue(cmp != 0)
+	case '<':
+		return BoolValue(cmp < 0)
+	case '>':
+		return BoolValue(cmp > 0)
+	case TLe:
+		return BoolValue(cmp <= 0)
+	default:
+		return BoolValue(cmp >= 0)
+	}
+}
+
+type unary struct {
+	op    TokenType
+	col   int
+	value Expr
+}
+
+func (n unary) String() string {
+	return fmt.Sprintf("%s%s", n.op, n.value)
+}
+
+func (n unary) Eval(env *Env) (Value, error) {
+	val, err := n.value.Eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == '!' {
+		b, err := ValueBool(val)
+		if err != nil {
+			return nil, NewError(n.col, err)
+		}
+		return BoolValue(!b), nil
+	}
+	switch val.Type() {
+	case TypeInt8:
+		ival, err := ValueInt8(val)
+		if err != nil {
+			return nil, err
+		}
+		var result int8
+		switch n.op {
+		case '-':
+			result = -ival
+		case '~':
+			result = ^ival
+		default:
+			return nil, NewError(n.col, fmt.Errorf("unsupported %s unary %s",
+				val.Type(), n.op))
+		}
+		return Int8Value(result), nil
+
+	case TypeInt16:
+		ival, err := ValueInt16(val)
+		if err != nil {
+			return nil, err
+		}
+		var result int16
+		switch n.op {
+		case '-':
+			result = -ival
+		case '~':
+			result = ^ival
+		default:
+			return nil, NewError(n.col, fmt.Errorf("unsupported %s unary %s",
+				val.Type(), n.op))
+		}
+		return Int16Value(result), nil
+
+	case TypeInt32:
+		ival, err := ValueInt32(val)
+		if err != nil {
+			return nil, err
+		}
+		var result int32
+		switch n.op {
+		case '-':
+			result = -ival
+		case '~':
+			result = ^ival
+		default:
+			return nil, NewError(n.col, fmt.Errorf("unsupported %s unary %s",
+				val.Type(), n.op))
+		}
+		return Int32Value(result), nil
+
+	case TypeInt64:
+		ival, err := ValueInt64(val)
+		if err != nil {
+			return nil, err
+		}
+		var result int64
+		switch n.op {
+		case '-':
+			result = -ival
+		case '~':
+			result = ^ival
+		default:
+			return nil, NewError(n.col, fmt.Errorf("unsupported %s unary %s",
+				val.Type(), n.op))
+		}
+		return Int64Value(result), nil
+
+	case TypeUint8, TypeUint16, TypeUint32, TypeUint64, TypeInt128,
+		TypeUint128:
+		ival, err := ValueBigInt(val)
+		if err != nil {
+			return nil, err
+		}
+		switch n.op {
+		case '-':
+			// Unsigned negation wraps around at the word size.
+			return NewIntValue(val.Type(), ival.Neg(ival))
+		case '~':
+			return NewIntValue(val.Type(), ival.Not(ival))
+		default:
+			return nil, NewError(n.col, fmt.Errorf("unsupported %s unary %s",
+				val.Type(), n.op))
+		}
+
+	case TypeFloat64:
+		ival, err := ValueFloat64(val)
+		if err != nil {
+			return nil, err
+		}
+		var result float64
+		switch n.op {
+		case '-':
+			result = -ival
+		default:
+			return nil, NewError(n.col, fmt.Errorf("unsupported %s unary %s",
+				val.Type(), n.op))
+		}
+		return Float64Value(result), nil
+
+	case TypeBigFloat:
+		ival, err := ValueBigFloat(val)
+		if err != nil {
+			return nil, err
+		}
+		result := env.Config().NewFloat()
+		switch n.op {
+		case '-':
+			result = result.Neg(ival)
+		default:
+			return nil, NewError(n.col, fmt.Errorf("unsupported %s unary %s",
+				val.Type(), n.op))
+		}
+		return BigFloatValue{
+			f: result,
+		}, nil
+
+	case TypeRational:
+		rval := val.(RationalValue)
+		switch n.op {
+		case '-':
+			return RationalValue{
+				r: new(big.Rat).Neg(rval.r),
+			}, nil
+		default:
+			return nil, NewError(n.col, fmt.Errorf("unsupported %s unary %s",
+				val.Type(), n.op))
+		}
+
+	case TypeComplex:
+		cval := val.(ComplexValue)
+		switch n.op {
+		case '-':
+			return -cval, nil
+		default:
+			return nil, NewError(n.col, fmt.Errorf("unsupported %s unary %s",
+				val.Type(), n.op))
+		}
+
+	case TypeFixed:
+		fval := val.(FixedValue)
+		switch n.op {
+		case '-':
+			return FixedValue{
+				raw: fixedLimit(new(big.Int).Neg(big.NewInt(fval.raw)),
+					fval.bits, env.Config().Saturate),
+				frac: fval.frac,
+				bits: fval.bits,
+			}, nil
+		default:
+			return nil, NewError(n.col, fmt.Errorf("unsupported %s unary %s",
+				val.Type(), n.op))
+		}
+
+	case TypeIP:
+		ipval := val.(IPValue)
+		switch n.op {
+		case '~':
+			return ipval.not(), nil
+		default:
+			return nil, NewError(n.col, fmt.Errorf("unsupported %s unary %s",
+				val.Type(), n.op))
+		}
+
+	case TypeMAC:
+		macval := val.(MACValue)
+		switch n.op {
+		case '~':
+			return macval.not(), nil
+		default:
+			return nil, NewError(n.col, fmt.Errorf("unsupported %s unary %s",
+				val.Type(), n.op))
+		}
+
+	case TypeUnit:
+		uval := val.(UnitValue)
+		switch n.op {
+		case '-':
+			return UnitValue{
+				r:    new(big.Rat).Neg(uval.r),
+				unit: uval.unit,
+			}, nil
+		default:
+			return nil, NewError(n.col, fmt.Errorf("unsupported %s unary %s",
+				val.Type(), n.op))
+		}
+
+	case TypeDuration:
+		dval := val.(DurationValue)
+		switch n.op {
+		case '-':
+			return DurationValue{
+				r: new(big.Rat).Neg(dval.r),
+			}, nil
+		default:
+			return nil, NewError(n.col, fmt.Errorf("unsupported %s unary %s",
+				val.Type(), n.op))
+		}
+
+	default:
+		return nil,
+			NewError(n.col, fmt.Errorf("unsupport %s value %s for unary %s",
+				val.Type(), val, n.op))
+	}
+}
+
+type variable struct {
+	name string
+	col  int
+}
+
+func (v variable) String() string {
+	return v.name
+}
+
+func (v variable) Eval(env *Env) (Value, error) {
+	val, ok := env.Get(v.name)
+	if !ok {
+		return nil, NewError(v.col, fmt.Errorf("undefined variable '%s'",
+			v.name))
+	}
+	return val, nil
+}
+
+// logical implements the short-circuit logical operators && and
+// ||. The right operand is evaluated only if the left operand does
+// not determine the result.
+type logical struct {
+	op    TokenType
+	col   int
+	left  Expr
+	right Expr
+}
+
+func (l logical) String() string {
+	return fmt.Sprintf("%s %s %s", l.left, l.op, l.right)
+}
+
+func (l logical) Eval(env *Env) (Value, error) {
+	v, err := l.left.Eval(env)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ValueBool(v)
+	if err != nil {
+		return nil, NewError(l.col, err)
+	}
+	if l.op == TOr && b {
+		return BoolValue(true), nil
+	}
+	if l.op == TAnd && !b {
+		return BoolValue(false), nil
+	}
+	v, err = l.right.Eval(env)
+	if err != nil {
+		return nil, err
+	}
+	b, err = ValueBool(v)
+	if err != nil {
+		return nil, NewError(l.col, err)
+	}
+	return BoolValue(b), nil
+}
+
+// conditional implements the conditional operator cond ? ifTrue :
+// ifFalse. Only the selected branch is evaluated.
+type conditional struct {
+	col     int
+	cond    Expr
+	ifTrue  Expr
+	ifFalse Expr
+}
+
+func (c conditional) String() string {
+	return fmt.Sprintf("%s ? %s : %s", c.cond, c.ifTrue, c.ifFalse)
+}
+
+func (c conditional) Eval(env *Env) (Value, error) {
+	v, err := c.cond.Eval(env)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ValueBool(v)
+	if err != nil {
+		return nil, NewError(c.col, err)
+	}
+	if b {
+		return c.ifTrue.Eval(env)
+	}
+	return c.ifFalse.Eval(env)
+}
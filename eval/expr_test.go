@@ -0,0 +1,2105 @@
+//
+// Copyright (c) 2019 Markku Rossi
+//
+// All rights reserved.
+//
+
+package eval
+
+import (
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testEnv = NewEnv()
+
+type exprTest struct {
+	in  string
+	out string
+}
+
+var exprTests = []exprTest{
+	{
+		in:  "42",
+		out: "42",
+	},
+	{
+		in:  "42.1",
+		out: "42.1",
+	},
+	{
+		in:  "42+1",
+		out: "43",
+	},
+	{
+		in:  "42.1+1",
+		out: "43.1",
+	},
+	{
+		in:  ".1",
+		out: "0.1",
+	},
+	{
+		in:  "0.1",
+		out: "0.1",
+	},
+	{
+		in:  "1.1",
+		out: "1.1",
+	},
+	{
+		in:  "-42",
+		out: "-42",
+	},
+	{
+		in:  "-42.1",
+		out: "-42.1",
+	},
+	{
+		in:  "42 + 11",
+		out: "53",
+	},
+	{
+		in:  "42+11",
+		out: "53",
+	},
+	{
+		in:  "42-11",
+		out: "31",
+	},
+	{
+		in:  "42.1+11",
+		out: "53.1",
+	},
+	{
+		in:  "42.1-11",
+		out: "31.1",
+	},
+	{
+		in:  "10/4",
+		out: "2",
+	},
+	{
+		in:  "10.0/4",
+		out: "2.5",
+	},
+	{
+		in:  "10*4",
+		out: "40",
+	},
+	{
+		in:  "10.1*4",
+		out: "40.4",
+	},
+	{
+		in:  "42%10",
+		out: "2",
+	},
+	{
+		in:  "(1+2+3+4)/4",
+		out: "2",
+	},
+	{
+		in:  "(1+2+3+4)/4.0",
+		out: "2.5",
+	},
+	{
+		in:  "2**32 - 1",
+		out: "4294967295",
+	},
+	{
+		in:  "2**3**2",
+		out: "512",
+	},
+	{
+		in:  "-2**2",
+		out: "-4",
+	},
+	{
+		in:  "2**-1",
+		out: "0",
+	},
+	{
+		in:  "(-2)**63",
+		out: "-9223372036854775808",
+	},
+	{
+		in:  "(-1)**3000000001",
+		out: "-1",
+	},
+	{
+		in:  "2.0**-1",
+		out: "0.5",
+	},
+	{
+		in:  "2.0**0.5",
+		out: "1.414213562373095048801688724209698",
+	},
+	{
+		in:  "10.0**3",
+		out: "1000",
+	},
+	{
+		in:  "2*3**2",
+		out: "18",
+	},
+	{
+		in:  "1 < 2",
+		out: "true",
+	},
+	{
+		in:  "1 + 1 == 2 && 2.5 >= 2",
+		out: "true",
+	},
+	{
+		in:  "1 != 1 || 3 <= 2",
+		out: "false",
+	},
+	{
+		in:  "!0",
+		out: "true",
+	},
+	{
+		in:  "1 || 1/0",
+		out: "true",
+	},
+	{
+		in:  "0 && 1/0",
+		out: "false",
+	},
+	{
+		in:  "1 < 2 ? 10 : 1/0",
+		out: "10",
+	},
+	{
+		in:  "0 ? 1 : 0 ? 2 : 3",
+		out: "3",
+	},
+	{
+		in:  "1e3",
+		out: "1000",
+	},
+	{
+		in:  "1e-9",
+		out: "0.000000001",
+	},
+	{
+		in:  "6.02e23",
+		out: "602000000000000000000000",
+	},
+	{
+		in:  ".5E+1",
+		out: "5",
+	},
+	{
+		in:  "0x1.8p3",
+		out: "12",
+	},
+	{
+		in:  "0x1p-2",
+		out: "0.25",
+	},
+	{
+		in:  "0o10.4",
+		out: "8.5",
+	},
+	{
+		in:  "-0b0.011",
+		out: "-0.375",
+	},
+	{
+		in:  "1_000_000",
+		out: "1000000",
+	},
+	{
+		in:  "0xdead_beef",
+		out: "3735928559",
+	},
+	{
+		in:  "0b1010_1010",
+		out: "170",
+	},
+	{
+		in:  "0o7_7 + 0_7",
+		out: "70",
+	},
+	{
+		in:  "4KiB",
+		out: "4 KiB",
+	},
+	{
+		in:  "1.5KiB + 4KiB",
+		out: "5.5 KiB",
+	},
+	{
+		in:  "2.5GB",
+		out: "2.5 GB",
+	},
+	{
+		in:  "2k",
+		out: "2000",
+	},
+	{
+		in:  "100ms",
+		out: "100ms",
+	},
+	{
+		in:  "3h + 1min",
+		out: "3h1m0s",
+	},
+	{
+		in:  "1e3ns",
+		out: "1µs",
+	},
+	{
+		in:  "4KiB / 1B",
+		out: "4096",
+	},
+	{
+		in:  "100Mbit / 1s",
+		out: "100 Mbit/s",
+	},
+	{
+		in:  "1GiB/s",
+		out: "1 GiB/s",
+	},
+	{
+		in:  "10MB/s * 2s",
+		out: "20000000 B",
+	},
+	{
+		in:  "(2s)**2 / 4s",
+		out: "1s",
+	},
+	{
+		in:  "1GiB / (100MB/s)",
+		out: "10.73741824s",
+	},
+	{
+		in:  "2 * 1.5h",
+		out: "3h0m0s",
+	},
+	{
+		in:  "8bit == 1B",
+		out: "true",
+	},
+	{
+		in:  "1MHz * 1ms",
+		out: "1000",
+	},
+	{
+		in:  "1/1ms",
+		out: "1000 Hz",
+	},
+	{
+		in:  "(2s)**2",
+		out: "4 s^2",
+	},
+	{
+		in:  "3+4i",
+		out: "3+4i",
+	},
+	{
+		in:  "(1+2i) * (3-1i)",
+		out: "5+5i",
+	},
+	{
+		in:  "(1i)**2",
+		out: "-1+0i",
+	},
+	{
+		in:  "abs(3+4i)",
+		out: "5",
+	},
+	{
+		in:  "real(3+4i) + imag(3+4i)",
+		out: "7",
+	},
+	{
+		in:  "conj(1-2.5i)",
+		out: "1+2.5i",
+	},
+	{
+		in:  "arg(1i) * 2",
+		out: "3.141592653589793",
+	},
+	{
+		in:  "-(1+1i) == -1-1i",
+		out: "true",
+	},
+	{
+		in:  "abs(-5)",
+		out: "5",
+	},
+	{
+		in:  "q(15, 0.5)",
+		out: "0.5",
+	},
+	{
+		in:  "q(15, 0.75) + q(15, 0.5)",
+		out: "0.999969482421875",
+	},
+	{
+		in:  "-q(15, -1)",
+		out: "0.999969482421875",
+	},
+	{
+		in:  "q(8, 1.5, 16) * q(8, 2.25, 16)",
+		out: "3.375",
+	},
+	{
+		in:  "q(15, 0.5) / q(15, -0.75)",
+		out: "-0.666656494140625",
+	},
+	{
+		in:  "q(15, 0.25) * 3",
+		out: "0.75",
+	},
+	{
+		in:  "fromq(15, 0x4000)",
+		out: "0.5",
+	},
+	{
+		in:  "raw(q(15, -0.25))",
+		out: "-8192",
+	},
+	{
+		in:  "q(15, 0.5) == 0.5",
+		out: "true",
+	},
+	{
+		in:  "q(15, 2) == 5",
+		out: "false",
+	},
+	{
+		in:  "q(15, -1) == -100",
+		out: "false",
+	},
+	{
+		in:  "q(15, 2) < 5",
+		out: "true",
+	},
+	{
+		in:  "-100 < q(15, -1)",
+		out: "true",
+	},
+	{
+		in:  "q(15, 0.5) != 0.50001",
+		out: "true",
+	},
+	{
+		in:  "q(15, 0.5) ** 2",
+		out: "0.25",
+	},
+	{
+		in:  "q(15, 0.5) ** -1",
+		out: "0.999969482421875",
+	},
+	{
+		in:  "q(15, 0.75) % q(15, 0.5)",
+		out: "0.25",
+	},
+	{
+		in:  "q(15, -0.75) % 0.5",
+		out: "-0.25",
+	},
+	{
+		in:  `"hello" + ", " + "world"`,
+		out: "hello, world",
+	},
+	{
+		in:  `len("héllo")`,
+		out: "5",
+	},
+	{
+		in:  `substr("héllo", 1, 2)`,
+		out: "él",
+	},
+	{
+		in:  `substr("日本語", 2)`,
+		out: "語",
+	},
+	{
+		in:  `index("日本語", "語")`,
+		out: "2",
+	},
+	{
+		in:  `len(pack(0x4142, 2, "be"))`,
+		out: "2",
+	},
+	{
+		in:  `unpack(substr(pack(0x4142, 2, "be"), 1), "be")`,
+		out: "66",
+	},
+	{
+		in:  `substr("hello", 1, 3) + substr("hello", 4)`,
+		out: "ello",
+	},
+	{
+		in:  `index("hello", "ll") + index("hello", "x")`,
+		out: "1",
+	},
+	{
+		in:  `upper("abc") + lower("DEF")`,
+		out: "ABCdef",
+	},
+	{
+		in:  `ord("€") == 0x20ac`,
+		out: "true",
+	},
+	{
+		in:  `chr(65) + chr(0x20ac)`,
+		out: "A€",
+	},
+	{
+		in:  `hex("AB\n")`,
+		out: "41420a",
+	},
+	{
+		in:  `unhex("41" + "42")`,
+		out: "AB",
+	},
+	{
+		in:  `unpack(pack(0x1234, 2, "le"), "be")`,
+		out: "13330",
+	},
+	{
+		in:  `hex(pack(-2, 4, "be"))`,
+		out: "fffffffe",
+	},
+	{
+		in:  `unpack(unhex("ffffffffffffffff"), "le")`,
+		out: "-1",
+	},
+	{
+		in:  `"a\tb\"" == "a" + chr(9) + "b" + chr(34)`,
+		out: "true",
+	},
+	{
+		in:  `"abc" < "abd"`,
+		out: "true",
+	},
+	{
+		in:  `'\x41' + '\101'`,
+		out: "130",
+	},
+	{
+		in:  `'é'`,
+		out: "233",
+	},
+	{
+		in:  `'\U0001F600'`,
+		out: "128512",
+	},
+	{
+		in:  `'\0'`,
+		out: "0",
+	},
+	{
+		in:  `'\u00e9' == 'é'`,
+		out: "true",
+	},
+	{
+		in:  `'\''`,
+		out: "39",
+	},
+	{
+		in:  `hex("\xff\u00e9\0\101")`,
+		out: "ffc3a90041",
+	},
+	{
+		in:  `"\"\\"`,
+		out: `"\`,
+	},
+	{
+		in:  `base64("hello")`,
+		out: "aGVsbG8=",
+	},
+	{
+		in:  `unbase64("aGVsbG8")`,
+		out: "hello",
+	},
+	{
+		in:  `hex(unbase64("-_8="))`,
+		out: "fbff",
+	},
+	{
+		in:  `base32("hi") + unbase32("NBUQ")`,
+		out: "NBUQ====hi",
+	},
+	{
+		in:  `url("a b/c?d&e=f")`,
+		out: "a+b%2Fc%3Fd%26e%3Df",
+	},
+	{
+		in:  `unurl("a%20b+c")`,
+		out: "a b c",
+	},
+	{
+		in:  `qp("caf\u00e9=")`,
+		out: "caf=C3=A9=3D",
+	},
+	{
+		in:  `unqp("caf=C3=A9") == "café"`,
+		out: "true",
+	},
+	{
+		in:  `base64(0x414243)`,
+		out: "QUJD",
+	},
+	{
+		in:  `hex(-1) + hex(0)`,
+		out: "ffffffffffffffff00",
+	},
+	{
+		in:  `hex(0x1234)`,
+		out: "1234",
+	},
+	{
+		in:  `crc32("123456789")`,
+		out: "3421780262",
+	},
+	{
+		in:  `crc32c("123456789")`,
+		out: "3808858755",
+	},
+	{
+		in:  `crc64("123456789") == 0x995dc9bbdf1939fa`,
+		out: "true",
+	},
+	{
+		in:  `crc16("123456789")`,
+		out: "47933",
+	},
+	{
+		in:  `crc8("123456789")`,
+		out: "244",
+	},
+	{
+		in:  `crc(16, 0x1021, 0xffff, 0, 0, 0, "123456789")`,
+		out: "10673",
+	},
+	{
+		in:  `crc(5, 0x05, 0x1f, 1, 1, 0x1f, "123456789")`,
+		out: "25",
+	},
+	{
+		in:  `crc(32, 0x04c11db7, -1, 1, 1, -1, "123456789") == crc32("123456789")`,
+		out: "true",
+	},
+	{
+		in:  `adler32("Wikipedia")`,
+		out: "300286872",
+	},
+	{
+		in:  `fnv32a("") + fnv32("a")`,
+		out: "2250832707",
+	},
+	{
+		in:  `md5("")`,
+		out: "d41d8cd98f00b204e9800998ecf8427e",
+	},
+	{
+		in:  `sha1("abc")`,
+		out: "a9993e364706816aba3e25717850c26c9cd0d89d",
+	},
+	{
+		in:  `sha256(0x616263) == sha256("abc")`,
+		out: "true",
+	},
+	{
+		in:  `sha3_256("")`,
+		out: "a7ffc6f8bf1ed76651c14756a061d662f580ff4de43b49fa82d80a4b80f8434a",
+	},
+	{
+		in:  `0xf0 | 0x0f`,
+		out: "255",
+	},
+	{
+		in:  `6 & 3 ^ 1`,
+		out: "3",
+	},
+	{
+		in:  `1 | 2 ^ 3 & 6`,
+		out: "1",
+	},
+	{
+		in:  `~0`,
+		out: "-1",
+	},
+	{
+		in:  `-1 & 0xff`,
+		out: "255",
+	},
+	{
+		in:  `10.0.0.1`,
+		out: "10.0.0.1",
+	},
+	{
+		in:  `10.0.0.255 + 1`,
+		out: "10.0.1.0",
+	},
+	{
+		in:  `1 + 10.0.0.1 - 2`,
+		out: "10.0.0.0",
+	},
+	{
+		in:  `10.0.0.5 - 10.0.0.1`,
+		out: "4",
+	},
+	{
+		in:  `10.1.2.3 & 255.255.255.0`,
+		out: "10.1.2.0",
+	},
+	{
+		in:  `10.1.2.3 & ~0xff | 1`,
+		out: "10.1.2.1",
+	},
+	{
+		in:  `~255.255.240.0`,
+		out: "0.0.15.255",
+	},
+	{
+		in:  `10.0.0.0/24 + 1`,
+		out: "10.0.1.0/24",
+	},
+	{
+		in:  `10.0.0.7/24 - 1`,
+		out: "9.255.255.0/24",
+	},
+	{
+		in:  `fe80::1`,
+		out: "fe80::1",
+	},
+	{
+		in:  `::1 + 0xffff`,
+		out: "::1:0",
+	},
+	{
+		in:  `2001:db8::/32`,
+		out: "2001:db8::/32",
+	},
+	{
+		in:  `::ffff:192.0.2.1`,
+		out: "::ffff:192.0.2.1",
+	},
+	{
+		in:  `1 ? fe80::1 : ::2`,
+		out: "fe80::1",
+	},
+	{
+		in:  `10.0.0.1 < 10.0.0.2`,
+		out: "true",
+	},
+	{
+		in:  `network(10.1.2.3/20)`,
+		out: "10.1.0.0",
+	},
+	{
+		in:  `broadcast(10.1.2.3/20)`,
+		out: "10.1.15.255",
+	},
+	{
+		in:  `netmask(10.1.2.3/20)`,
+		out: "255.255.240.0",
+	},
+	{
+		in:  `hosts(10.0.0.0/24) + hosts(10.0.0.0/31)`,
+		out: "256",
+	},
+	{
+		in:  `hosts(fe80::/64)`,
+		out: "18446744073709551616",
+	},
+	{
+		in:  `hosts(::/0) - 1`,
+		out: "340282366920938463463374607431768211455",
+	},
+	{
+		in:  `contains(10.0.0.0/8, 10.2.3.4)`,
+		out: "true",
+	},
+	{
+		in:  `contains(10.0.0.0/8, 10.1.0.0/16)`,
+		out: "true",
+	},
+	{
+		in:  `contains(10.1.0.0/16, 10.0.0.0/8)`,
+		out: "false",
+	},
+	{
+		in:  `subnet(10.0.0.0/16, 8, 5)`,
+		out: "10.0.5.0/24",
+	},
+	{
+		in:  `subnet(fd00::/48, 16, 0xff)`,
+		out: "fd00:0:0:ff::/64",
+	},
+	{
+		in:  `ip(0x7f000001)`,
+		out: "127.0.0.1",
+	},
+	{
+		in:  `ip(1, 6)`,
+		out: "::1",
+	},
+	{
+		in:  `ip("192.168.0.0/16")`,
+		out: "192.168.0.0/16",
+	},
+	{
+		in:  `hex(10.0.0.1)`,
+		out: "0a000001",
+	},
+	{
+		in:  `00:1A:2b:3c:4d:5e`,
+		out: "00:1a:2b:3c:4d:5e",
+	},
+	{
+		in:  `10 - 20-30-40-50-60`,
+		out: "-190",
+	},
+	{
+		in:  `mac("10-20-30-40-50-60")`,
+		out: "10:20:30:40:50:60",
+	},
+	{
+		in:  `00-1a-2b-3c-4d-5e == 001a.2b3c.4d5e`,
+		out: "true",
+	},
+	{
+		in:  `00:1a:2b:3c:4d:ff + 1`,
+		out: "00:1a:2b:3c:4e:00",
+	},
+	{
+		in:  `aa:bb:cc:dd:ee:ff - aa:bb:cc:dd:ee:00`,
+		out: "255",
+	},
+	{
+		in:  `dead.beef.cafe & 0xffffff`,
+		out: "00:00:00:ef:ca:fe",
+	},
+	{
+		in:  `~ff:ff:ff:00:00:00`,
+		out: "00:00:00:ff:ff:ff",
+	},
+	{
+		in:  `1 ? 00:1a:2b:3c:4d:5e : 0`,
+		out: "00:1a:2b:3c:4d:5e",
+	},
+	{
+		in:  `10 - 20`,
+		out: "-10",
+	},
+	{
+		in:  `mac(0x001a2b3c4d5e)`,
+		out: "00:1a:2b:3c:4d:5e",
+	},
+	{
+		in:  `mac("02-1a-2b-ff-fe-3c-4d-5e")`,
+		out: "02:1a:2b:ff:fe:3c:4d:5e",
+	},
+	{
+		in:  `eui64(00:1a:2b:3c:4d:5e)`,
+		out: "02:1a:2b:ff:fe:3c:4d:5e",
+	},
+	{
+		in:  `linklocal(00:1a:2b:3c:4d:5e)`,
+		out: "fe80::21a:2bff:fe3c:4d5e",
+	},
+	{
+		in:  `oui(00:1a:2b:3c:4d:5e)`,
+		out: "6699",
+	},
+	{
+		in:  `multicast(01:00:5e:00:00:01)`,
+		out: "true",
+	},
+	{
+		in:  `local(01:00:5e:00:00:01)`,
+		out: "false",
+	},
+	{
+		in:  `local(02:00:00:00:00:01)`,
+		out: "true",
+	},
+	{
+		in:  `hex(00:1a:2b:3c:4d:5e)`,
+		out: "001a2b3c4d5e",
+	},
+	{
+		in:  "2024-01-15T10:30:00Z",
+		out: "2024-01-15T10:30:00Z",
+	},
+	{
+		in:  "2024-01-15t10:30:00.123456789+02:00",
+		out: "2024-01-15T10:30:00.123456789+02:00",
+	},
+	{
+		in:  "2024-02-29T23:00-0130 + 1h",
+		out: "2024-03-01T00:00:00-01:30",
+	},
+	{
+		in:  "2024-01-15T10:30Z - 2024-01-15T08:00Z",
+		out: "2h30m0s",
+	},
+	{
+		in:  "2024-01-15T10:30Z - 0.5ms",
+		out: "2024-01-15T10:29:59.9995Z",
+	},
+	{
+		in:  "60 + 2024-01-15T10:30Z",
+		out: "2024-01-15T10:31:00Z",
+	},
+	{
+		in:  "2024-01-15T10:30Z == 2024-01-15T12:30+02:00",
+		out: "true",
+	},
+	{
+		in:  "2024-01-15T10:30Z < 2024-01-15T10:30:00.5Z",
+		out: "true",
+	},
+	{
+		in:  "1 ? 2024-01-15T10:30Z : 0",
+		out: "2024-01-15T10:30:00Z",
+	},
+	{
+		in:  "unix(2023-11-14T22:13:20Z)",
+		out: "1700000000",
+	},
+	{
+		in:  `unix(2023-11-14T22:13:20.25Z, "ms")`,
+		out: "1700000000250",
+	},
+	{
+		in:  `unix(1970-01-01T00:00:00.000000001Z, "ns")`,
+		out: "1",
+	},
+	{
+		in:  `unix(date("2023-11-14T22:13:20Z"))`,
+		out: "1700000000",
+	},
+	{
+		in:  "unix(date(1700000000))",
+		out: "1700000000",
+	},
+	{
+		in:  "now() > 2024-01-01T00:00Z",
+		out: "true",
+	},
+	{
+		in:  "1h30m",
+		out: "1h30m0s",
+	},
+	{
+		in:  "250ms",
+		out: "250ms",
+	},
+	{
+		in:  "3.5us",
+		out: "3.5µs",
+	},
+	{
+		in:  "1d12h + 90s",
+		out: "36h1m30s",
+	},
+	{
+		in:  "-1m0.5s",
+		out: "-1m0.5s",
+	},
+	{
+		in:  "1h30m0s == 90min",
+		out: "true",
+	},
+	{
+		in:  "1h30m / 4",
+		out: "22m30s",
+	},
+	{
+		in:  "1h30m / 10min",
+		out: "9",
+	},
+	{
+		in:  "10ms + 1s",
+		out: "1.01s",
+	},
+	{
+		in:  "abs(-2s)",
+		out: "2s",
+	},
+	{
+		in:  "0s",
+		out: "0s",
+	},
+	{
+		in:  "1000000d",
+		out: "24000000h0m0s",
+	},
+	{
+		in:  "1s / 3",
+		out: "333.333333ms",
+	},
+	{
+		in:  "2024-01-15T10:30Z + 1h30m",
+		out: "2024-01-15T12:00:00Z",
+	},
+	{
+		in:  "2024-01-15T10:30Z - 250ms",
+		out: "2024-01-15T10:29:59.75Z",
+	},
+	{
+		in:  "ticks(10ms, 48MHz)",
+		out: "480000",
+	},
+	{
+		in:  "ticks(1s, 32768)",
+		out: "32768",
+	},
+	{
+		in:  "ticks(1ms, 32.768kHz)",
+		out: "32.768",
+	},
+	{
+		in:  "48MHz * 10ms",
+		out: "480000",
+	},
+}
+
+func TestExpr(t *testing.T) {
+	for idx, test := range exprTests {
+		expr, err := Parse(test.in)
+		if err != nil {
+			t.Errorf("test %d: failed to parse '%s': %s", idx, test.in, err)
+			continue
+		}
+		val, err := expr.Eval(testEnv)
+		if err != nil {
+			t.Errorf("test %d: eval failed: %s", idx, err)
+			continue
+		}
+		out := val.String()
+		if out != test.out {
+			t.Errorf("test %d: unexpected result '%s', expected '%s'",
+				idx, out, test.out)
+		}
+	}
+}
+
+var exprErrorTests = []string{
+	"2**63",
+	"(-2)**64",
+	"1 << -1",
+	"1 >> -1",
+	"3**3000000000",
+	"(-3)**3000000000",
+	"0**-1",
+	"(-2.0)**0.5",
+	"1/0",
+	"1 ? 1/0 : 1",
+	"undefined(1)",
+	"1s + 1B",
+	"4KiB + 1",
+	"1 - 10ms",
+	"2 ** 1KiB",
+	"1B < 1Hz",
+	"1s ** 1s",
+	"s",
+	"d",
+	"2 * B",
+	"1GiB / s",
+	"1i < 2",
+	"q(15, 0.5) + q(31, 0.5)",
+	"q(64, 1)",
+	"q(15, 1) / 0",
+	"q(15, 0.5) ** 0.5",
+	"q(15, 0.5) % 0",
+	`"a" + 1`,
+	`"a" * "b"`,
+	`substr("abc", 4)`,
+	`substr("abc", 1, 3)`,
+	`len(pack(0xdeadbeef, 4, "be"))`,
+	`substr(pack(0xdeadbeef, 4, "be"), 0, 2)`,
+	`index("a\xffb", "b")`,
+	`ord("ab")`,
+	`unhex("4")`,
+	`pack(1, 2, "middle")`,
+	`unpack(unhex("010000000000000000"), "be")`,
+	`unbase64("!!")`,
+	`unbase32("1")`,
+	`unurl("%zz")`,
+	`base64(1.5)`,
+	`crc(65, 1, 0, 0, 0, 0, "a")`,
+	`md5(1.5)`,
+	"1.5 & 1",
+	"255.255.255.255 + 1",
+	"0.0.0.0 - 1",
+	"10.0.0.1 == ::1",
+	"10.0.0.1 + 10.0.0.1",
+	"10.0.0.0/8 & 0xff",
+	"10.0.0.1 & 0x100000000",
+	"network(10.0.0.1)",
+	"subnet(10.0.0.0/16, 8, 256)",
+	"ip(-1)",
+	"ip(1, 5)",
+	"ff:ff:ff:ff:ff:ff + 1",
+	"1 - 00:00:00:00:00:01",
+	"00:00:00:00:00:01 + 10.0.0.1",
+	"00:00:00:00:00:01 & 0x1000000000000",
+	"eui64(eui64(00:1a:2b:3c:4d:5e))",
+	`mac("00:1a:2b")`,
+	"oui(1)",
+	"2024-01-15T10:30Z + 2024-01-15T10:30Z",
+	"2024-01-15T10:30Z * 2",
+	"2024-01-15T10:30Z + 1kB",
+	"1 - 2024-01-15T10:30Z",
+	`date("2024-01-15 10:30")`,
+	`date(1, "m")`,
+	"unix(1)",
+	"1h + 1B",
+	"ticks(1B, 1MHz)",
+	"ticks(1s, 1s)",
+}
+
+func TestExprError(t *testing.T) {
+	for idx, test := range exprErrorTests {
+		expr, err := Parse(test)
+		if err != nil {
+			t.Errorf("test %d: failed to parse '%s': %s", idx, test, err)
+			continue
+		}
+		val, err := expr.Eval(testEnv)
+		if err == nil {
+			t.Errorf("test %d: eval of '%s' succeeded: %s", idx, test, val)
+		}
+	}
+}
+
+var parseErrorTests = []struct {
+	in  string
+	col int
+}{
+	{
+		in:  "1__0",
+		col: 2,
+	},
+	{
+		in:  "1_ + 2",
+		col: 1,
+	},
+	{
+		in:  "0x1.p",
+		col: 5,
+	},
+	{
+		in:  "1e+",
+		col: 3,
+	},
+	{
+		in:  "0b102",
+		col: 4,
+	},
+	{
+		in:  "09",
+		col: 1,
+	},
+	{
+		in:  "010.4",
+		col: 3,
+	},
+	{
+		in:  "0b1.2",
+		col: 4,
+	},
+	{
+		in:  "0x",
+		col: 2,
+	},
+	{
+		in:  "1 2",
+		col: 2,
+	},
+	{
+		in:  "1.2.3",
+		col: 3,
+	},
+	{
+		in:  "10E",
+		col: 0,
+	},
+	{
+		in:  `"a\q"`,
+		col: 3,
+	},
+	{
+		in:  `'\400'`,
+		col: 2,
+	},
+	{
+		in:  `'\uD800'`,
+		col: 2,
+	},
+	{
+		in:  `'\x4'`,
+		col: 4,
+	},
+	{
+		in:  `'\q'`,
+		col: 2,
+	},
+	{
+		in:  "1 + 10.0.0.256",
+		col: 4,
+	},
+	{
+		in:  "10.0.0.0/33",
+		col: 0,
+	},
+	{
+		in:  "010.0.0.1",
+		col: 0,
+	},
+	{
+		in:  "1 + 2024-13-01",
+		col: 4,
+	},
+	{
+		in:  "00-11-22-33-44-55",
+		col: 0,
+	},
+	{
+		in:  "1 + 10-20-30-40-50-60",
+		col: 4,
+	},
+	{
+		in:  "2023-02-29",
+		col: 0,
+	},
+	{
+		in:  "2024-01-15T24:00Z",
+		col: 0,
+	},
+	{
+		in:  "1h30",
+		col: 2,
+	},
+	{
+		in:  "1h30kB",
+		col: 2,
+	},
+}
+
+func TestParseError(t *testing.T) {
+	for idx, test := range parseErrorTests {
+		_, err := Parse(test.in)
+		if err == nil {
+			t.Errorf("test %d: parse of '%s' succeeded", idx, test.in)
+			continue
+		}
+		if Column(err) != test.col {
+			t.Errorf("test %d: error '%s' at column %d, expected %d",
+				idx, err, Column(err), test.col)
+		}
+	}
+}
+
+var formatTests = []struct {
+	in      string
+	options Options
+	out     string
+}{
+	{
+		in:      "1536",
+		options: Options{Human: true},
+		out:     "1.5 KiB",
+	},
+	{
+		in:      "1000",
+		options: Options{Human: true},
+		out:     "1000 B",
+	},
+	{
+		in:      "2.5GiB + 1B",
+		options: Options{Human: true},
+		out:     "2.5 GiB",
+	},
+	{
+		in:      "1EiB",
+		options: Options{Human: true},
+		out:     "1 EiB",
+	},
+	{
+		in:      "100ms",
+		options: Options{Human: true, HumanTime: true},
+		out:     "100 ms",
+	},
+	{
+		in:      "90min",
+		options: Options{Human: true, HumanTime: true},
+		out:     "1.5 h",
+	},
+	{
+		in:      "2d + 12h",
+		options: Options{Human: true, HumanTime: true},
+		out:     "2.5 d",
+	},
+	{
+		in:      "0",
+		options: Options{Human: true, HumanTime: true},
+		out:     "0 s",
+	},
+	{
+		in:      "1.5GiB",
+		options: Options{Human: true},
+		out:     "1.5 GiB",
+	},
+	{
+		in:      "90min",
+		options: Options{Human: true},
+		out:     "1.5 h",
+	},
+	{
+		in:      "4KiB",
+		options: Options{Base: Base16},
+		out:     "0x1000 B",
+	},
+	{
+		in:      "10ms",
+		options: Options{Base: Base16},
+		out:     "0x989680 ns",
+	},
+	{
+		in:      "250ms",
+		options: Options{Base: Base8, String: true},
+		out:     "250ms",
+	},
+	{
+		in:      "1.5s",
+		options: Options{Base: Base10, Locale: localeC},
+		out:     "1.5s",
+	},
+	{
+		in:      "rat(-7, 2)",
+		options: Options{Base: Base10, Rational: RationalMixed},
+		out:     "-3 1/2",
+	},
+	{
+		in:      "rat(3, 4)",
+		options: Options{Base: Base10, Rational: RationalMixed},
+		out:     "3/4",
+	},
+	{
+		in:      "rat(1, 3)",
+		options: Options{Base: Base10, Rational: RationalDecimal},
+		out:     "0.33333333333333333333",
+	},
+	{
+		in:      "rat(3, 8)",
+		options: Options{Base: Base10, Rational: RationalDecimal},
+		out:     "0.375",
+	},
+	{
+		in:      "rat(2, 3)",
+		options: Options{Base: Base10, Rational: RationalDecimal, Digits: 3},
+		out:     "0.667",
+	},
+	{
+		in:      "rat(255, 16)",
+		options: Options{Base: Base16},
+		out:     "0xff/0x10",
+	},
+	{
+		in:      "3+4i",
+		options: Options{Base: Base10, Complex: ComplexPolar},
+		out:     "5∠0.9272952180016122",
+	},
+	{
+		in:      "1.5-2i",
+		options: Options{Base: Base10, Locale: locales["de"]},
+		out:     "1,5-2i",
+	},
+	{
+		in:      "255.5",
+		options: Options{Base: Base16},
+		out:     "0xff.8",
+	},
+	{
+		in:      "-0.375",
+		options: Options{Base: Base2},
+		out:     "-0b0.011",
+	},
+	{
+		in:      "8.5",
+		options: Options{Base: Base8},
+		out:     "0o10.4",
+	},
+	{
+		in:      "1.0/3",
+		options: Options{Base: Base16, Digits: 4},
+		out:     "0x0.5555",
+	},
+	{
+		in:      "2.75",
+		options: Options{Base: BaseBinary},
+		out:     "10.11",
+	},
+	{
+		in:      "0.1",
+		options: Options{Base: Base16},
+		out:     "0x0.1999999999999999999999999999a",
+	},
+	{
+		in:      "1.0",
+		options: Options{Base: BaseHexFloat},
+		out:     "0x1p+0",
+	},
+	{
+		in:      "-0.0078125",
+		options: Options{Base: BaseHexFloat},
+		out:     "-0x1p-7",
+	},
+	{
+		in:      "12.0",
+		options: Options{Base: BaseHexFloat},
+		out:     "0x1.8p+3",
+	},
+	{
+		in:      "mpfloat(1.0/1024)",
+		options: Options{Base: BaseHexFloat},
+		out:     "0x1p-10",
+	},
+	{
+		in:      "255.5",
+		options: Options{Base: BaseHexFloat},
+		out:     "0x1.ffp+7",
+	},
+	{
+		in:      "mpfloat(255.5)",
+		options: Options{Base: BaseHexFloat},
+		out:     "0x1.ffp+7",
+	},
+	{
+		in:      "q(15, 0.5)",
+		options: Options{Base: Base10, Raw: true},
+		out:     "0.5 [0x4000 Q0.15]",
+	},
+	{
+		in:      "-42",
+		options: Options{Base: Base16},
+		out:     "0xffffffffffffffd6",
+	},
+	{
+		in:      "-5",
+		options: Options{Base: Base8},
+		out:     "01777777777777777777773",
+	},
+	{
+		in:      "-42",
+		options: Options{Base: Base16, Magnitude: true},
+		out:     "-0x2a",
+	},
+	{
+		in:      "-5",
+		options: Options{Base: Base2, Magnitude: true},
+		out:     "-0b101",
+	},
+	{
+		in:      "-q(15, 0.5)",
+		options: Options{Base: Base16},
+		out:     "0xc000",
+	},
+	{
+		in:      "-q(15, 0.5)",
+		options: Options{Base: Base10, Raw: true, Magnitude: true},
+		out:     "-0.5 [0xc000 Q0.15]",
+	},
+	{
+		in:      "'A'",
+		options: Options{Base: Base10},
+		out:     "65",
+	},
+	{
+		in:      `"hello"`,
+		options: Options{Encoding: EncodingBase64},
+		out:     "aGVsbG8=",
+	},
+	{
+		in:      "0xdeadbeef",
+		options: Options{Encoding: EncodingHex},
+		out:     "deadbeef",
+	},
+	{
+		in:      "-2",
+		options: Options{Encoding: EncodingBase32},
+		out:     "7777777777774===",
+	},
+	{
+		in:      "10.0.0.1",
+		options: Options{Base: Base16},
+		out:     "0xa000001",
+	},
+	{
+		in:      "10.0.0.0/8",
+		options: Options{Base: Base2},
+		out:     "0b1010000000000000000000000000/8",
+	},
+	{
+		in:      "fe80::1",
+		options: Options{Encoding: EncodingBase64},
+		out:     "/oAAAAAAAAAAAAAAAAAAAQ==",
+	},
+	{
+		in:      "00:1a:2b:3c:4d:5e",
+		options: Options{Base: Base10, MAC: MACDot},
+		out:     "001a.2b3c.4d5e",
+	},
+	{
+		in:      "00:1a:2b:3c:4d:5e",
+		options: Options{Base: Base10, MAC: MACDash},
+		out:     "00-1a-2b-3c-4d-5e",
+	},
+	{
+		in:      "00:1a:2b:3c:4d:5e",
+		options: Options{Base: Base10, MAC: MACBare},
+		out:     "001a2b3c4d5e",
+	},
+	{
+		in:      "00:1a:2b:3c:4d:5e",
+		options: Options{Base: Base16},
+		out:     "0x1a2b3c4d5e",
+	},
+	{
+		in:      "2023-11-14T22:13:20Z",
+		options: Options{Base: Base16},
+		out:     "0x6553f100",
+	},
+	{
+		in:      "2023-11-14T22:13:20Z",
+		options: Options{Human: true},
+		out:     "Tue, 14 Nov 2023 22:13:20 UTC",
+	},
+	{
+		in:      "2023-11-14T22:13:20Z",
+		options: Options{Base: Base10, Location: time.FixedZone("", 7200)},
+		out:     "2023-11-15T00:13:20+02:00",
+	},
+}
+
+func TestFormat(t *testing.T) {
+	for idx, test := range formatTests {
+		expr, err := Parse(test.in)
+		if err != nil {
+			t.Errorf("test %d: failed to parse '%s': %s", idx, test.in, err)
+			continue
+		}
+		val, err := expr.Eval(testEnv)
+		if err != nil {
+			t.Errorf("test %d: eval failed: %s", idx, err)
+			continue
+		}
+		out := val.Format(test.options)
+		if out != test.out {
+			t.Errorf("test %d: unexpected result '%s', expected '%s'",
+				idx, out, test.out)
+		}
+	}
+}
+
+var checkFormatTests = []struct {
+	in      string
+	options Options
+	ok      bool
+}{
+	{
+		in:      "1h",
+		options: Options{Base: Base16},
+		ok:      true,
+	},
+	{
+		in:      "1h",
+		options: Options{Base: Base8, String: true},
+	},
+	{
+		in:      "1h",
+		options: Options{Base: BaseHexFloat},
+	},
+	{
+		in:      "1h",
+		options: Options{Base: Base10, Encoding: EncodingBase64},
+	},
+	{
+		in:      "3600",
+		options: Options{Base: Base8, String: true},
+		ok:      true,
+	},
+}
+
+func TestCheckFormat(t *testing.T) {
+	for idx, test := range checkFormatTests {
+		expr, err := Parse(test.in)
+		if err != nil {
+			t.Errorf("test %d: failed to parse '%s': %s", idx, test.in, err)
+			continue
+		}
+		val, err := expr.Eval(testEnv)
+		if err != nil {
+			t.Errorf("test %d: eval failed: %s", idx, err)
+			continue
+		}
+		err = CheckFormat(val, test.options)
+		if (err == nil) != test.ok {
+			t.Errorf("test %d: CheckFormat(%s) returned %v", idx, test.in, err)
+		}
+	}
+}
+
+var localeTests = []struct {
+	locale string
+	in     string
+	out    string
+}{
+	{
+		locale: "C",
+		in:     "1234567 + 0.5",
+		out:    "1234567.5",
+	},
+	{
+		locale: "en",
+		in:     "1,234,567 + 0.5",
+		out:    "1,234,567.5",
+	},
+	{
+		locale: "en",
+		in:     "-1234",
+		out:    "-1,234",
+	},
+	{
+		locale: "fi",
+		in:     "1 234,5 * 2",
+		out:    "2 469",
+	},
+	{
+		locale: "de",
+		in:     "1.234,5",
+		out:    "1.234,5",
+	},
+}
+
+func TestLocale(t *testing.T) {
+	for idx, test := range localeTests {
+		config := NewConfig()
+		err := config.Set("locale", test.locale)
+		if err != nil {
+			t.Fatalf("test %d: failed to set locale: %s", idx, err)
+		}
+		expr, err := ParseConfig(test.in, config)
+		if err != nil {
+			t.Errorf("test %d: failed to parse '%s': %s", idx, test.in, err)
+			continue
+		}
+		val, err := expr.Eval(testEnv)
+		if err != nil {
+			t.Errorf("test %d: eval failed: %s", idx, err)
+			continue
+		}
+		out := val.Format(config.Options())
+		if out != test.out {
+			t.Errorf("test %d: unexpected result '%s', expected '%s'",
+				idx, out, test.out)
+		}
+	}
+}
+
+var defineTests = []struct {
+	def  string
+	call string
+	out  string
+}{
+	{
+		def:  "clamp(x, lo, hi) = x < lo ? lo : x > hi ? hi : x",
+		call: "clamp(-5, 0, 10)",
+		out:  "0",
+	},
+	{
+		def:  "fact(n) = n <= 1 ? 1 : n * fact(n - 1)",
+		call: "fact(10)",
+		out:  "3628800",
+	},
+}
+
+func TestDefine(t *testing.T) {
+	env := NewEnv()
+	for idx, test := range defineTests {
+		f, err := NewParser(NewStringInput(test.def)).ParseDefinition()
+		if err != nil {
+			t.Fatalf("test %d: failed to parse '%s': %s", idx, test.def, err)
+		}
+		err = env.Define(f)
+		if err != nil {
+			t.Fatalf("test %d: define failed: %s", idx, err)
+		}
+		expr, err := Parse(test.call)
+		if err != nil {
+			t.Fatalf("test %d: failed to parse '%s': %s", idx, test.call, err)
+		}
+		val, err := expr.Eval(env)
+		if err != nil {
+			t.Fatalf("test %d: eval failed: %s", idx, err)
+		}
+		if val.String() != test.out {
+			t.Errorf("test %d: unexpected result '%s', expected '%s'",
+				idx, val, test.out)
+		}
+	}
+}
+
+func TestExprConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			env := NewEnv()
+			for idx, test := range exprTests {
+				expr, err := Parse(test.in)
+				if err != nil {
+					t.Errorf("test %d: failed to parse '%s': %s",
+						idx, test.in, err)
+					return
+				}
+				val, err := expr.Eval(env)
+				if err != nil {
+					t.Errorf("test %d: eval failed: %s", idx, err)
+					return
+				}
+				if val.String() != test.out {
+					t.Errorf("test %d: unexpected result '%s', expected '%s'",
+						idx, val, test.out)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+var convertTests = []struct {
+	in   string
+	unit string
+	out  string
+}{
+	{
+		in:   "100Mbit/s",
+		unit: "MiB/s",
+		out:  "11.9209289550781 MiB/s",
+	},
+	{
+		in:   "1d",
+		unit: "min",
+		out:  "1440 min",
+	},
+	{
+		in:   "4096",
+		unit: "KiB",
+		out:  "4 KiB",
+	},
+	{
+		in:   "1kHz",
+		unit: "Hz",
+		out:  "1000 Hz",
+	},
+	{
+		in:   "1h30m",
+		unit: "min",
+		out:  "90 min",
+	},
+	{
+		in:   "250ms",
+		unit: "us",
+		out:  "250000 us",
+	},
+}
+
+func TestConvert(t *testing.T) {
+	for idx, test := range convertTests {
+		expr, err := Parse(test.in)
+		if err != nil {
+			t.Errorf("test %d: failed to parse '%s': %s", idx, test.in, err)
+			continue
+		}
+		val, err := expr.Eval(testEnv)
+		if err != nil {
+			t.Errorf("test %d: eval failed: %s", idx, err)
+			continue
+		}
+		unit, err := ParseUnit(test.unit)
+		if err != nil {
+			t.Errorf("test %d: failed to parse unit '%s': %s",
+				idx, test.unit, err)
+			continue
+		}
+		val, err = Convert(val, unit)
+		if err != nil {
+			t.Errorf("test %d: convert failed: %s", idx, err)
+			continue
+		}
+		out := val.String()
+		if out != test.out {
+			t.Errorf("test %d: unexpected result '%s', expected '%s'",
+				idx, out, test.out)
+		}
+	}
+	_, err := Convert(NewUnitValue(big.NewRat(1, 1), units["s"]), units["B"])
+	if err == nil {
+		t.Errorf("convert of incompatible units succeeded")
+	}
+}
+
+var exactTests = []struct {
+	in  string
+	out string
+}{
+	{
+		in:  "1/3 + 1/6",
+		out: "1/2",
+	},
+	{
+		in:  "48000000/7 * 7",
+		out: "48000000",
+	},
+	{
+		in:  "(2/3)**-2",
+		out: "9/4",
+	},
+	{
+		in:  "-(5/4) % (1/2)",
+		out: "-1/4",
+	},
+	{
+		in:  "1/3 < 0.34",
+		out: "true",
+	},
+	{
+		in:  "rat(0.1) + rat(0.2) == rat(3, 10)",
+		out: "true",
+	},
+	{
+		in:  "num(6/4) * 10 + den(6/4)",
+		out: "32",
+	},
+	{
+		in:  "7.0/2",
+		out: "3.5",
+	},
+}
+
+func TestExact(t *testing.T) {
+	env := NewEnv()
+	err := env.Config().Set("exact", "on")
+	if err != nil {
+		t.Fatalf("failed to set exact: %s", err)
+	}
+	for idx, test := range exactTests {
+		expr, err := Parse(test.in)
+		if err != nil {
+			t.Errorf("test %d: failed to parse '%s': %s", idx, test.in, err)
+			continue
+		}
+		val, err := expr.Eval(env)
+		if err != nil {
+			t.Errorf("test %d: eval failed: %s", idx, err)
+			continue
+		}
+		out := val.String()
+		if out != test.out {
+			t.Errorf("test %d: unexpected result '%s', expected '%s'",
+				idx, out, test.out)
+		}
+	}
+	expr, err := Parse("1/0")
+	if err != nil {
+		t.Fatalf("failed to parse: %s", err)
+	}
+	_, err = expr.Eval(env)
+	if err == nil {
+		t.Errorf("exact division by zero succeeded")
+	}
+}
+
+var configTests = []struct {
+	settings [][2]string
+	in       string
+	out      string
+}{
+	{
+		settings: [][2]string{{"precision", "24"}},
+		in:       "1.0/3",
+		out:      "0.33333334",
+	},
+	{
+		settings: [][2]string{{"precision", "8"}},
+		in:       "1.0 + 0.001",
+		out:      "1",
+	},
+	{
+		in:  "1.0/3",
+		out: "0.3333333333333333333333333333333333",
+	},
+	{
+		settings: [][2]string{{"precision", "2048"}},
+		in:       "1.0 * rat(1, 3) - 1.0/3",
+		out:      "0",
+	},
+	{
+		settings: [][2]string{{"precision", "2048"}},
+		in:       "rat(1, 3) == 1.0/3",
+		out:      "true",
+	},
+	{
+		settings: [][2]string{{"precision", "2048"}},
+		in:       "mpfloat(rat(1, 3)) - 1.0/3",
+		out:      "0",
+	},
+	{
+		settings: [][2]string{{"digits", "4"}},
+		in:       "2.0/3",
+		out:      "0.6667",
+	},
+	{
+		settings: [][2]string{{"digits", "4"}, {"rounding", "ToZero"}},
+		in:       "2.0/3",
+		out:      "0.6666",
+	},
+	{
+		settings: [][2]string{{"digits", "2"}, {"rounding", "toNegativeInf"}},
+		in:       "-1.0/3",
+		out:      "-0.34",
+	},
+	{
+		settings: [][2]string{{"digits", "1"}},
+		in:       "0.25",
+		out:      "0.2",
+	},
+	{
+		settings: [][2]string{{"digits", "1"}, {"rounding", "ToNearestAway"}},
+		in:       "0.25",
+		out:      "0.3",
+	},
+	{
+		settings: [][2]string{
+			{"exact", "on"},
+			{"rational", "decimal"},
+			{"digits", "3"},
+			{"rounding", "ToPositiveInf"},
+		},
+		in:  "1/3",
+		out: "0.334",
+	},
+	{
+		settings: [][2]string{{"saturate", "off"}},
+		in:       "q(15, 0.75) + q(15, 0.5)",
+		out:      "-0.75",
+	},
+	{
+		settings: [][2]string{{"saturate", "off"}},
+		in:       "-q(15, -1)",
+		out:      "-1",
+	},
+	{
+		settings: [][2]string{{"timezone", "UTC"}},
+		in:       "date(1700000000)",
+		out:      "2023-11-14T22:13:20Z",
+	},
+	{
+		settings: [][2]string{{"timezone", "UTC"}},
+		in:       `date(1700000000123, "ms")`,
+		out:      "2023-11-14T22:13:20.123Z",
+	},
+	{
+		settings: [][2]string{{"timezone", "UTC"}},
+		in:       "date(1.5min)",
+		out:      "1970-01-01T00:01:30Z",
+	},
+	{
+		settings: [][2]string{{"timezone", "UTC"}},
+		in:       "2024-01-15",
+		out:      "2024-01-15T00:00:00Z",
+	},
+	{
+		settings: [][2]string{{"timezone", "Asia/Tokyo"}},
+		in:       "2024-01-15T10:30",
+		out:      "2024-01-15T10:30:00+09:00",
+	},
+	{
+		settings: [][2]string{{"timezone", "Asia/Tokyo"}},
+		in:       "2024-01-15T10:30Z",
+		out:      "2024-01-15T19:30:00+09:00",
+	},
+}
+
+func TestConfig(t *testing.T) {
+	for idx, test := range configTests {
+		env := NewEnv()
+		for _, setting := range test.settings {
+			err := env.Config().Set(setting[0], setting[1])
+			if err != nil {
+				t.Fatalf("test %d: failed to set %s: %s", idx, setting[0], err)
+			}
+		}
+		expr, err := ParseConfig(test.in, env.Config())
+		if err != nil {
+			t.Errorf("test %d: failed to parse '%s': %s", idx, test.in, err)
+			continue
+		}
+		val, err := expr.Eval(env)
+		if err != nil {
+			t.Errorf("test %d: eval failed: %s", idx, err)
+			continue
+		}
+		out := val.Format(env.Config().Options())
+		if out != test.out {
+			t.Errorf("test %d: unexpected result '%s', expected '%s'",
+				idx, out, test.out)
+		}
+	}
+	for _, setting := range [][2]string{
+		{"precision", "0"},
+		{"precision", "bits"},
+		{"rounding", "up"},
+		{"digits", "-1"},
+		{"exact", "maybe"},
+		{"wordsize", "12"},
+		{"signed", "maybe"},
+		{"magnitude", "maybe"},
+		{"timezone", "Nowhere/Bogus"},
+	} {
+		err := NewConfig().Set(setting[0], setting[1])
+		if err == nil {
+			t.Errorf("set %s %s succeeded", setting[0], setting[1])
+		}
+	}
+}
+
+var wordSizeTests = []struct {
+	wordsize string
+	signed   string
+	in       string
+	base     Base
+	out      string
+}{
+	{"32", "on", "-1", Base16, "0xffffffff"},
+	{"32", "on", "0xffffffff", Base10, "-1"},
+	{"32", "on", "2147483647+1", Base10, "-2147483648"},
+	{"32", "off", "-1", Base10, "4294967295"},
+	{"32", "off", "1-2 < 0", Base10, "false"},
+	{"32", "off", "1<<40", Base10, "0"},
+	{"8", "on", "127+1", Base10, "-128"},
+	{"8", "on", "-5", Base2, "0b11111011"},
+	{"8", "on", "100*3", Base10, "44"},
+	{"8", "off", "255+2", Base10, "1"},
+	{"8", "off", "-1", Base8, "0377"},
+	{"16", "on", "-0x7fff-1", Base16, "0x8000"},
+	{"16", "off", "0xffff >> 4", Base16, "0xfff"},
+	{"64", "on", "-1", Base16, "0xffffffffffffffff"},
+	{"64", "off", "-1", Base10, "18446744073709551615"},
+	{"128", "on", "2**100", Base10, "1267650600228229401496703205376"},
+	{"128", "on", "-1", Base16, "0xffffffffffffffffffffffffffffffff"},
+	{"128", "on", "170141183460469231731687303715884105727+1", Base10,
+		"-170141183460469231731687303715884105728"},
+	{"128", "off", "2**128-1", Base10, "overflow"},
+	{"128", "off", "-1", Base10, "340282366920938463463374607431768211455"},
+	{"8", "on", "128", Base10, "overflow"},
+	{"8", "on", "-128", Base10, "-128"},
+	{"8", "on", "-128", Base16, "0x80"},
+	{"8", "on", "-128 / -1", Base10, "-128"},
+	{"8", "on", "-128 % -1", Base10, "0"},
+	{"8", "on", "-129", Base10, "overflow"},
+	{"8", "on", "-128**1", Base10, "overflow"},
+	{"64", "on", "-9223372036854775808", Base10, "-9223372036854775808"},
+	{"128", "on", "-170141183460469231731687303715884105728", Base10,
+		"-170141183460469231731687303715884105728"},
+	{"32", "off", "4294967296", Base10, "overflow"},
+	{"32", "on", "1.5+1", Base10, "2.5"},
+	{"32", "off", "~0", Base16, "0xffffffff"},
+	{"32", "on", "1 << -1", Base10, "overflow"},
+	{"32", "on", "1 >> -1", Base10, "overflow"},
+	{"16", "on", "1 << -1", Base10, "overflow"},
+	{"8", "on", "1 >> -1", Base10, "overflow"},
+	{"128", "on", "1 << -1", Base10, "overflow"},
+	{"128", "on", "~(1 << 100) & -1 ^ 3", Base10,
+		"-1267650600228229401496703205380"},
+}
+
+func TestWordSize(t *testing.T) {
+	for idx, test := range wordSizeTests {
+		env := NewEnv()
+		err := env.Config().Set("wordsize", test.wordsize)
+		if err != nil {
+			t.Fatalf("test %d: failed to set wordsize: %s", idx, err)
+		}
+		err = env.Config().Set("signed", test.signed)
+		if err != nil {
+			t.Fatalf("test %d: failed to set signed: %s", idx, err)
+		}
+		var out string
+		expr, err := ParseConfig(test.in, env.Config())
+		if err == nil {
+			var val Value
+			val, err = expr.Eval(env)
+			if err == nil {
+				options := env.Config().Options()
+				options.Base = test.base
+				out = val.Format(options)
+			}
+		}
+		if err != nil {
+			if test.out != "overflow" {
+				t.Errorf("test %d: '%s' failed: %s", idx, test.in, err)
+			}
+			continue
+		}
+		if out != test.out {
+			t.Errorf("test %d: unexpected result '%s', expected '%s'",
+				idx, out, test.out)
+		}
+	}
+}
+
+var floatRoundTripTests = []string{
+	"1.5",
+	"-255.5",
+	"0.1",
+	"1.0/3",
+	"1e-10",
+	"123456789.125",
+}
+
+func TestFloatRoundTrip(t *testing.T) {
+	for idx, test := range floatRoundTripTests {
+		for _, base := range []Base{Base2, Base8, Base16, BaseHexFloat} {
+			expr, err := Parse(test)
+			if err != nil {
+				t.Fatalf("test %d: failed to parse '%s': %s", idx, test, err)
+			}
+			val, err := expr.Eval(testEnv)
+			if err != nil {
+				t.Fatalf("test %d: eval failed: %s", idx, err)
+			}
+			out := val.Format(Options{Base: base})
+			expr, err = Parse(out)
+			if err != nil {
+				t.Errorf("test %d: failed to parse '%s': %s", idx, out, err)
+				continue
+			}
+			val2, err := expr.Eval(testEnv)
+			if err != nil {
+				t.Fatalf("test %d: eval failed: %s", idx, err)
+			}
+			if val2.String() != val.String() {
+				t.Errorf("test %d: %s round-tripped to %s via %s",
+					idx, val, val2, out)
+			}
+		}
+	}
+}
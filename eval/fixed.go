@@ -0,0 +1,311 @@
+//
+// Copyright (c) 2024 Markku Rossi
+//
+// All rights reserved.
+//
+
+package eval
+
+import (
+	"fmt"
+	"math/big"
+)
+
+var (
+	_ Value = FixedValue{}
+	_ Expr  = FixedValue{}
+)
+
+// FixedValue implements signed Qm.n fixed point numbers as Value. The
+// number is stored as a raw two's complement integer of the word size
+// bits. The n fraction bits define the scale of the raw integer and
+// the remaining m = bits-1-n bits hold the integer part.
+type FixedValue struct {
+	raw  int64
+	frac uint
+	bits uint
+}
+
+// NewFixedValue creates a fixed point number from the raw integer
+// value. The raw value is wrapped to the word size bits.
+func NewFixedValue(raw int64, frac, bits uint) (FixedValue, error) {
+	if bits != 8 && bits != 16 && bits != 32 && bits != 64 {
+		return FixedValue{}, fmt.Errorf("invalid fixed point word size %d",
+			bits)
+	}
+	if frac >= bits {
+		return FixedValue{}, fmt.Errorf("too many fraction bits %d for %d bits",
+			frac, bits)
+	}
+	return FixedValue{
+		raw:  wrapInt(big.NewInt(raw), bits),
+		frac: frac,
+		bits: bits,
+	}, nil
+}
+
+// fixedWordSize returns the smallest word size that can hold the
+// sign bit and frac fraction bits.
+func fixedWordSize(frac uint) uint {
+	for _, bits := range []uint{8, 16, 32, 64} {
+		if frac < bits {
+			return bits
+		}
+	}
+	return 0
+}
+
+// QFormat returns the value's fixed point format as Qm.n.
+func (v FixedValue) QFormat() string {
+	return fmt.Sprintf("Q%d.%d", v.bits-1-v.frac, v.frac)
+}
+
+// Raw returns the raw integer value as an integer Value of the word
+// size.
+func (v FixedValue) Raw() Value {
+	switch v.bits {
+	case 8:
+		return Int8Value(v.raw)
+	case 16:
+		return Int16Value(v.raw)
+	case 32:
+		return Int32Value(v.raw)
+	default:
+		return Int64Value(v.raw)
+	}
+}
+
+// Rat returns the value as a rational number.
+func (v FixedValue) Rat() *big.Rat {
+	return new(big.Rat).SetFrac(big.NewInt(v.raw),
+		new(big.Int).Lsh(bigOne, v.frac))
+}
+
+func (v FixedValue) String() string {
+	return v.Format(Options{
+		Base: Base10,
+	})
+}
+
+// Format implements Value.Format().
+func (v FixedValue) Format(options Options) string {
+	var str string
+	if options.Base == Base10 {
+		digits := options.Digits
+		if digits <= 0 {
+			digits, _ = decimalDigits(v.Rat().Denom())
+		}
+		str = options.Locale.localize(formatRatDigits(v.Rat(), digits,
+			options.Rounding))
+	} else {
+		str = formatInt(v.raw, v.bits, options)
+	}
+	if options.Raw {
+		rawOptions := options
+		rawOptions.Base = Base16
+		rawOptions.Magnitude = false
+		str += fmt.Sprintf(" [%s %s]", formatInt(v.raw, v.bits, rawOptions),
+			v.QFormat())
+	}
+	return str
+}
+
+// Type implements Value.Type().
+func (v FixedValue) Type() Type {
+	return TypeFixed
+}
+
+// Eval implements Expr.Eval().
+func (v FixedValue) Eval(env *Env) (Value, error) {
+	return v, nil
+}
+
+// wrapInt wraps the integer to a two's complement integer of bits
+// bits.
+func wrapInt(i *big.Int, bits uint) int64 {
+	return wrapBigInt(i, bits, true).Int64()
+}
+
+// wrapBigInt wraps the integer to a signed or unsigned integer of
+// bits bits.
+func wrapBigInt(i *big.Int, bits uint, signed bool) *big.Int {
+	mask := new(big.Int).Lsh(bigOne, bits)
+	mask.Sub(mask, bigOne)
+	u := new(big.Int).And(i, mask)
+	if signed && u.Bit(int(bits)-1) == 1 {
+		u.Sub(u, new(big.Int).Lsh(bigOne, bits))
+	}
+	return u
+}
+
+// fixedLimit converts the raw integer to the word size by saturating
+// or wrapping it.
+func fixedLimit(i *big.Int, bits uint, saturate bool) int64 {
+	if !saturate {
+		return wrapInt(i, bits)
+	}
+	max := new(big.Int).Lsh(bigOne, bits-1)
+	min := new(big.Int).Neg(max)
+	max.Sub(max, bigOne)
+	if i.Cmp(max) > 0 {
+		return max.Int64()
+	}
+	if i.Cmp(min) < 0 {
+		return min.Int64()
+	}
+	return i.Int64()
+}
+
+// withRaw returns a fixed point number with the format of v and the
+// raw value r. The raw value is rounded to an integer and saturated
+// or wrapped to the word size according to the configuration.
+func (v FixedValue) withRaw(r *big.Rat, config *Config) FixedValue {
+	return FixedValue{
+		raw:  fixedLimit(roundRat(r, config.Rounding), v.bits, config.Saturate),
+		frac: v.frac,
+		bits: v.bits,
+	}
+}
+
+// toFixed converts the value to the fixed point format of the
+// template value t.
+func toFixed(env *Env, value Value, t FixedValue) (FixedValue, error) {
+	if f, ok := value.(FixedValue); ok {
+		if f.frac != t.frac || f.bits != t.bits {
+			return FixedValue{}, fmt.Errorf(
+				"incompatible fixed point formats %s and %s",
+				f.QFormat(), t.QFormat())
+		}
+		return f, nil
+	}
+	r, err := ValueRat(value)
+	if err != nil {
+		return FixedValue{}, err
+	}
+	r.Mul(r, new(big.Rat).SetInt(new(big.Int).Lsh(bigOne, t.frac)))
+	return t.withRaw(r, env.Config()), nil
+}
+
+// evalFixed evaluates the binary operation where at least one of the
+// operands is a fixed point number. The other operand is converted to
+// the same fixed point format. The results saturate or wrap to the
+// word size according to the configuration. The comparisons compare
+// the exact values of the operands and the exponents of powers must
+// be integers.
+func (b binary) evalFixed(env *Env, v1, v2 Value) (Value, error) {
+	t, ok := v1.(FixedValue)
+	if !ok {
+		t = v2.(FixedValue)
+	}
+	switch b.op {
+	case TEq, TNeq, '<', '>', TLe, TGe:
+		r1, err := ValueRat(v1)
+		if err != nil {
+			return nil, NewError(b.col, err)
+		}
+		r2, err := ValueRat(v2)
+		if err != nil {
+			return nil, NewError(b.col, err)
+		}
+		return b.compareResult(r1.Cmp(r2)), nil
+
+	case TPower:
+		x, err := ValueRat(v1)
+		if err != nil {
+			return nil, NewError(b.col, err)
+		}
+		y, err := ValueRat(v2)
+		if err != nil {
+			return nil, NewError(b.col, err)
+		}
+		if !y.IsInt() || !y.Num().IsInt64() {
+			return nil, NewError(b.col,
+				fmt.Errorf("fixed point exponent must be an integer: %s", v2))
+		}
+		r, err := ratPow(x, y.Num().Int64())
+		if err != nil {
+			return nil, NewError(b.col, err)
+		}
+		r.Mul(r, new(big.Rat).SetInt(new(big.Int).Lsh(bigOne, t.frac)))
+		return t.withRaw(r, env.Config()), nil
+	}
+
+	f1, err := toFixed(env, v1, t)
+	if err != nil {
+		return nil, NewError(b.col, err)
+	}
+	f2, err := toFixed(env, v2, t)
+	if err != nil {
+		return nil, NewError(b.col, err)
+	}
+	config := env.Config()
+
+	// Scaling by plain numbers uses the exact number instead of its
+	// fixed point approximation.
+	_, fixed1 := v1.(FixedValue)
+	_, fixed2 := v2.(FixedValue)
+	if (b.op == '*' || b.op == '/') && fixed1 != fixed2 {
+		var r *big.Rat
+		if fixed1 {
+			r, err = ValueRat(v2)
+		} else {
+			r, err = ValueRat(v1)
+		}
+		if err != nil {
+			return nil, NewError(b.col, err)
+		}
+		if b.op == '/' {
+			if !fixed1 {
+				return nil, NewError(b.col,
+					fmt.Errorf("unsupport binary operand '%s' for %s",
+						b.op, t.QFormat()))
+			}
+			if r.Sign() == 0 {
+				return nil, NewError(b.col, errDivideByZero)
+			}
+			r.Inv(r)
+		}
+		r.Mul(r, new(big.Rat).SetInt64(t.raw))
+		return t.withRaw(r, config), nil
+	}
+
+	r1 := big.NewInt(f1.raw)
+	r2 := big.NewInt(f2.raw)
+	result := new(big.Int)
+
+	switch b.op {
+	case '+':
+		result.Add(r1, r2)
+
+	case '-':
+		result.Sub(r1, r2)
+
+	case '*':
+		// The product has 2n fraction bits; round it back to n bits.
+		p := new(big.Rat).SetFrac(result.Mul(r1, r2),
+			new(big.Int).Lsh(bigOne, t.frac))
+		result = roundRat(p, config.Rounding)
+
+	case '/':
+		if r2.Sign() == 0 {
+			return nil, NewError(b.col, errDivideByZero)
+		}
+		result.Quo(result.Lsh(r1, t.frac), r2)
+
+	case '%':
+		if r2.Sign() == 0 {
+			return nil, NewError(b.col, errDivideByZero)
+		}
+		result.Rem(r1, r2)
+
+	default:
+		return nil, NewError(b.col,
+			fmt.Errorf("unsupport binary operand '%s' for %s",
+				b.op, t.QFormat()))
+	}
+	return FixedValue{
+		raw:  fixedLimit(result, t.bits, config.Saturate),
+		frac: t.frac,
+		bits: t.bits,
+	}, nil
+}
@@ -0,0 +1,176 @@
+//
+// Copyright (c) 2024 Markku Rossi
+//
+// All rights reserved.
+//
+
+package eval
+
+import (
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/adler32"
+	"hash/crc32"
+	"hash/crc64"
+	"hash/fnv"
+	"math/big"
+	"math/bits"
+)
+
+// CRC defines the parameters of a CRC algorithm in the Rocksoft
+// model. The init value is the initial value of the register in the
+// direct algorithm which processes the input bits without augmenting
+// zero bits.
+type CRC struct {
+	Width  uint
+	Poly   uint64
+	Init   uint64
+	RefIn  bool
+	RefOut bool
+	XorOut uint64
+}
+
+// CRC algorithms of the crc8 and crc16 builtins.
+var (
+	// crc8 is the CRC-8 algorithm with the check value 0xf4.
+	crc8 = CRC{
+		Width: 8,
+		Poly:  0x07,
+	}
+	// crc16 is the CRC-16/ARC algorithm with the check value 0xbb3d.
+	crc16 = CRC{
+		Width:  16,
+		Poly:   0x8005,
+		RefIn:  true,
+		RefOut: true,
+	}
+)
+
+var (
+	crc32c = crc32.MakeTable(crc32.Castagnoli)
+	crc64e = crc64.MakeTable(crc64.ECMA)
+)
+
+// Constructors of the hash.Hash32 and hash.Hash64 checksums as
+// hash.Hash for the checksumBuiltin.
+func newAdler32() hash.Hash { return adler32.New() }
+func newCRC32() hash.Hash   { return crc32.NewIEEE() }
+func newCRC32C() hash.Hash  { return crc32.New(crc32c) }
+func newCRC64() hash.Hash   { return crc64.New(crc64e) }
+func newFNV32() hash.Hash   { return fnv.New32() }
+func newFNV32a() hash.Hash  { return fnv.New32a() }
+func newFNV64() hash.Hash   { return fnv.New64() }
+func newFNV64a() hash.Hash  { return fnv.New64a() }
+
+// Checksum computes the CRC of the data.
+func (crc CRC) Checksum(data []byte) uint64 {
+	top := uint64(1) << (crc.Width - 1)
+	mask := top<<1 - 1
+
+	reg := crc.Init & mask
+	for _, b := range data {
+		if crc.RefIn {
+			b = bits.Reverse8(b)
+		}
+		for i := 7; i >= 0; i-- {
+			msb := reg&top != 0
+			reg = (reg << 1) & mask
+			if msb != ((b>>uint(i))&1 == 1) {
+				reg ^= crc.Poly & mask
+			}
+		}
+	}
+	if crc.RefOut {
+		reg = bits.Reverse64(reg) >> (64 - crc.Width)
+	}
+	return (reg ^ crc.XorOut) & mask
+}
+
+func builtinCRC(bi *Builtin, env *Env) (Value, error) {
+	width, err := bi.intArg(env, 0)
+	if err != nil {
+		return nil, err
+	}
+	if width < 1 || width > 64 {
+		return nil, NewError(bi.col,
+			fmt.Errorf("%s: invalid width %d, expected 1-64 bits",
+				bi.name, width))
+	}
+	crc := CRC{
+		Width: uint(width),
+	}
+	crc.Poly, err = bi.uint64Arg(env, 1)
+	if err != nil {
+		return nil, err
+	}
+	crc.Init, err = bi.uint64Arg(env, 2)
+	if err != nil {
+		return nil, err
+	}
+	crc.RefIn, err = bi.boolArg(env, 3)
+	if err != nil {
+		return nil, err
+	}
+	crc.RefOut, err = bi.boolArg(env, 4)
+	if err != nil {
+		return nil, err
+	}
+	crc.XorOut, err = bi.uint64Arg(env, 5)
+	if err != nil {
+		return nil, err
+	}
+	data, err := bi.dataArg(env, 6)
+	if err != nil {
+		return nil, err
+	}
+	return bi.intResult(env, new(big.Int).SetUint64(crc.Checksum(data)),
+		true)
+}
+
+// crcBuiltin creates a builtin function that computes the CRC of
+// strings and integers.
+func crcBuiltin(crc CRC) func(bi *Builtin, env *Env) (Value, error) {
+	return func(bi *Builtin, env *Env) (Value, error) {
+		data, err := bi.dataArg(env, 0)
+		if err != nil {
+			return nil, err
+		}
+		return bi.intResult(env,
+			new(big.Int).SetUint64(crc.Checksum(data)), true)
+	}
+}
+
+// checksumBuiltin creates a builtin function that computes the 32-bit
+// or 64-bit checksum of strings and integers and returns it as an
+// integer.
+func checksumBuiltin(newHash func() hash.Hash) func(bi *Builtin,
+	env *Env) (Value, error) {
+
+	return func(bi *Builtin, env *Env) (Value, error) {
+		data, err := bi.dataArg(env, 0)
+		if err != nil {
+			return nil, err
+		}
+		h := newHash()
+		h.Write(data)
+		sum := new(big.Int).SetBytes(h.Sum(nil))
+		return bi.intResult(env, sum, true)
+	}
+}
+
+// hashBuiltin creates a builtin function that computes the hash of
+// strings and integers and returns it as a hexadecimal string.
+func hashBuiltin(newHash func() hash.Hash) func(bi *Builtin,
+	env *Env) (Value, error) {
+
+	return func(bi *Builtin, env *Env) (Value, error) {
+		data, err := bi.dataArg(env, 0)
+		if err != nil {
+			return nil, err
+		}
+		h := newHash()
+		h.Write(data)
+		return StringValue(hex.EncodeToString(h.Sum(nil))), nil
+	}
+}
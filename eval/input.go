@@ -0,0 +1,819 @@
+//
+// Copyright (c) 2020-2024 Markku Rossi
+//
+// All rights reserved.
+//
+
+package eval
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"unicode"
+)
+
+// Input implements command input and output handler. The input reads
+// lines from its Readline. An input without a Readline reads a single
+// string and returns io.ErrUnexpectedEOF if more input is requested.
+type Input struct {
+	prompt   string
+	line     []rune
+	col      int
+	ungot    *Token
+	readline Readline
+	config   *Config
+}
+
+var defaultConfig = NewConfig()
+
+// TokenType specifies token types.
+type TokenType int
+
+// Command tokens.
+const (
+	TIdentifier TokenType = iota + 256
+	TInteger
+	TFloat
+	TUnit
+	TComplex
+	TString
+	TAddress
+	TTime
+	TLeftShift
+	TRightShift
+	TPower
+	TEq
+	TNeq
+	TLe
+	TGe
+	TAnd
+	TOr
+)
+
+var tokenTypes = map[TokenType]string{
+	TIdentifier: "identifier",
+	TInteger:    "integer",
+	TFloat:      "float",
+	TUnit:       "unit value",
+	TComplex:    "complex",
+	TString:     "string",
+	TAddress:    "address",
+	TTime:       "time",
+	TLeftShift:  "<<",
+	TRightShift: ">>",
+	TPower:      "**",
+	TEq:         "==",
+	TNeq:        "!=",
+	TLe:         "<=",
+	TGe:         ">=",
+	TAnd:        "&&",
+	TOr:         "||",
+}
+
+// operators define the two-character operators. The map is indexed
+// with the operator's first and second runes.
+var operators = map[rune]map[rune]TokenType{
+	'*': {'*': TPower},
+	'<': {'<': TLeftShift, '=': TLe},
+	'>': {'>': TRightShift, '=': TGe},
+	'=': {'=': TEq},
+	'!': {'=': TNeq},
+	'&': {'&': TAnd},
+	'|': {'|': TOr},
+}
+
+func (t TokenType) String() string {
+	name, ok := tokenTypes[t]
+	if ok {
+		return name
+	}
+	if t < TIdentifier {
+		return fmt.Sprintf("%c", rune(t))
+	}
+	return fmt.Sprintf("{TokenType %d}", t)
+}
+
+// Token specifies command token value.
+type Token struct {
+	Column     int
+	Type       TokenType
+	StrVal     string
+	IntVal     Expr
+	FloatVal   Expr
+	UnitVal    Expr
+	ComplexVal Expr
+	AddrVal    Expr
+	TimeVal    Expr
+	// NegIntVal is the negated value of the decimal integer literal
+	// which fits in the integer type only as a negative number, such
+	// as 128 in 8-bit words. The IntVal of such literals is nil.
+	NegIntVal Expr
+}
+
+func (t *Token) String() string {
+	switch t.Type {
+	case TIdentifier:
+		return t.StrVal
+
+	case TInteger:
+		if t.IntVal == nil {
+			return t.StrVal
+		}
+		return fmt.Sprintf("%v", t.IntVal)
+
+	case TFloat:
+		return fmt.Sprintf("%v", t.FloatVal)
+
+	case TUnit:
+		return fmt.Sprintf("%v", t.UnitVal)
+
+	case TComplex:
+		return fmt.Sprintf("%v", t.ComplexVal)
+
+	case TString:
+		return strconv.Quote(t.StrVal)
+
+	case TAddress:
+		return fmt.Sprintf("%v", t.AddrVal)
+
+	case TTime:
+		return fmt.Sprintf("%v", t.TimeVal)
+
+	default:
+		return t.Type.String()
+	}
+}
+
+// NewInput creates a new I/O handler.
+func NewInput(prompt string, readline Readline) (*Input, error) {
+	return &Input{
+		prompt:   prompt,
+		readline: readline,
+		config:   defaultConfig,
+	}, nil
+}
+
+// NewStringInput creates a new input for the string.
+func NewStringInput(input string) *Input {
+	return &Input{
+		line:   append([]rune(input), '\n'),
+		config: defaultConfig,
+	}
+}
+
+// SetConfig sets the configuration that controls how the input
+// tokens are parsed.
+func (in *Input) SetConfig(config *Config) {
+	in.config = config
+}
+
+// Close closes the input.
+func (in *Input) Close() {
+	if in.readline != nil {
+		in.readline.Close()
+	}
+}
+
+// FlushEOL discards the current input line.
+func (in *Input) FlushEOL() {
+	in.ungot = nil
+	in.line = []rune{}
+}
+
+// ExpectEOL returns an error if the input line has tokens left.
+func (in *Input) ExpectEOL() error {
+	if !in.HasToken() {
+		return nil
+	}
+	t, err := in.GetToken()
+	if err != nil {
+		return err
+	}
+	return NewError(t.Column, fmt.Errorf("unexpected token '%s'", t))
+}
+
+// HasToken tests if input has any tokens without prompting user.
+func (in *Input) HasToken() bool {
+	if in.ungot != nil {
+		return true
+	}
+
+	for in.HasRune() {
+		r, _, err := in.Rune(false)
+		if err != nil {
+			return false
+		}
+		if !unicode.IsSpace(r) {
+			in.UngetRune(r)
+			return true
+		}
+	}
+	return false
+}
+
+// GetFirstToken returns the next input token which is the first token
+// of a command.
+func (in *Input) GetFirstToken() (*Token, error) {
+	return in.getToken(true)
+}
+
+// GetToken returns the next input token which is a subsequent token
+// in a command.
+func (in *Input) GetToken() (*Token, error) {
+	return in.getToken(false)
+}
+
+func (in *Input) getToken(first bool) (*Token, error) {
+	var r rune
+	var col, c int
+	var err error
+
+	if in.ungot != nil {
+		ret := in.ungot
+		in.ungot = nil
+		return ret, nil
+	}
+
+	for {
+		r, col, err = in.Rune(first)
+		if err != nil {
+			return nil, NewError(col, err)
+		}
+		if !unicode.IsSpace(r) {
+			break
+		}
+	}
+	if isDecimalDigit(r) {
+		t, err := in.readTime(first, col, r)
+		if t != nil || err != nil {
+			return t, err
+		}
+	}
+	if isAddressStart(r) {
+		t, err := in.readAddress(first, col, r)
+		if t != nil || err != nil {
+			return t, err
+		}
+	}
+	switch r {
+	case '/', '%', '+', '-', '(', ')', ',', '?', ':', '^', '~':
+		return &Token{
+			Column: col,
+			Type:   TokenType(r),
+		}, nil
+
+	case '*', '<', '>', '=', '!', '&', '|':
+		n, _, err := in.Rune(first)
+		if err != nil {
+			return nil, NewError(col, err)
+		}
+		tt, ok := operators[r][n]
+		if ok {
+			return &Token{
+				Column: col,
+				Type:   tt,
+			}, nil
+		}
+		in.UngetRune(n)
+		return &Token{
+			Column: col,
+			Type:   TokenType(r),
+		}, nil
+
+	case '\'':
+		ch, chCol, err := in.Rune(first)
+		if err != nil {
+			return nil, NewError(col, err)
+		}
+		if ch == '\\' {
+			ch, _, err = in.readEscape(first, '\'', "char")
+			if err != nil {
+				return nil, err
+			}
+		}
+		r, col, err = in.Rune(first)
+		if err != nil {
+			return nil, NewError(col, err)
+		}
+		if r != '\'' {
+			return nil, NewError(chCol,
+				fmt.Errorf("unexpected character '%c' in char literal", r))
+		}
+		return &Token{
+			Column: chCol,
+			Type:   TInteger,
+			IntVal: Int32Value(ch),
+		}, nil
+
+	case '"':
+		return in.readStringLiteral(first, col)
+
+	case '.':
+		r, _, err = in.Rune(first)
+		if err != nil {
+			return nil, NewError(col, err)
+		}
+		in.UngetRune(r)
+		if in.config.Locale.Decimal == '.' && isDecimalDigit(r) {
+			return in.readDecimalLiteral(first, col, []rune{'.'})
+		}
+		return &Token{
+			Column: col,
+			Type:   TokenType('.'),
+		}, nil
+
+	case '0':
+		r, c, err = in.Rune(first)
+		if err != nil {
+			return nil, NewError(c, err)
+		}
+		switch r {
+		case 'b', 'B':
+			return in.readBinaryLiteral(col, []rune{'0', r})
+		case 'o', 'O':
+			return in.readOctalLiteral(col, []rune{'0', r})
+		case 'x', 'X':
+			return in.readHexLiteral(col, []rune{'0', r})
+		case '0', '1', '2', '3', '4', '5', '6', '7', '_':
+			in.UngetRune(r)
+			return in.readOctalLiteral(col, []rune{'0'})
+		case '8', '9':
+			return nil, NewError(c,
+				fmt.Errorf("invalid digit '%c' in octal literal", r))
+		default:
+			in.UngetRune(r)
+			if r == in.config.Locale.Decimal || r == 'e' || r == 'E' {
+				return in.readDecimalLiteral(first, col, []rune{'0'})
+			}
+			suffix := in.readSuffix()
+			if suffix != nil {
+				return in.scaleLiteral(col, "0", suffix)
+			}
+		}
+		return in.intLiteral(col, new(big.Int), false)
+
+	default:
+		if unicode.IsLetter(r) {
+			id := []rune{r}
+			for {
+				r, c, err = in.Rune(first)
+				if err != nil {
+					return nil, NewError(c, err)
+				}
+				if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+					id = append(id, r)
+				} else {
+					in.UngetRune(r)
+					return &Token{
+						Column: col,
+						Type:   TIdentifier,
+						StrVal: string(id),
+					}, nil
+				}
+			}
+		}
+		if unicode.IsDigit(r) {
+			return in.readDecimalLiteral(first, col, []rune{r})
+		}
+		return nil, NewError(col, fmt.Errorf("unexpected character '%c'", r))
+	}
+}
+
+func isBinaryDigit(r rune) bool {
+	return r == '0' || r == '1'
+}
+
+func isOctalDigit(r rune) bool {
+	return '0' <= r && r <= '7'
+}
+
+func isDecimalDigit(r rune) bool {
+	return '0' <= r && r <= '9'
+}
+
+func isHexDigit(r rune) bool {
+	return unicode.Is(unicode.Hex_Digit, r)
+}
+
+// readDigits reads digits, accepted by isDigit, and the '_' digit
+// separators into val. The separators must separate successive
+// digits. The argument prefix specifies if the digits follow a base
+// prefix, in which case the digits can start with a separator. The
+// function returns the number of digits read.
+func (in *Input) readDigits(val []rune, isDigit func(r rune) bool,
+	prefix bool) ([]rune, int, error) {
+
+	var count int
+	var sepCol int
+	var sep bool
+	prevDigit := prefix
+
+	for {
+		r, c, err := in.Rune(false)
+		if err != nil {
+			return nil, 0, NewError(c, err)
+		}
+		if r == '_' {
+			if !prevDigit {
+				return nil, 0, NewError(c,
+					errors.New("'_' must separate successive digits"))
+			}
+			prevDigit = false
+			sep = true
+			sepCol = c
+			continue
+		}
+		if !isDigit(r) {
+			in.UngetRune(r)
+			if sep {
+				return nil, 0, NewError(sepCol,
+					errors.New("'_' must separate successive digits"))
+			}
+			return val, count, nil
+		}
+		val = append(val, r)
+		count++
+		prevDigit = true
+		sep = false
+	}
+}
+
+// checkLiteralEnd checks that the next input rune does not continue
+// the integer literal with digits that are invalid in its base.
+func (in *Input) checkLiteralEnd(name string) error {
+	r, c, err := in.Rune(false)
+	if err != nil {
+		return NewError(c, err)
+	}
+	in.UngetRune(r)
+	if isDecimalDigit(r) {
+		return NewError(c,
+			fmt.Errorf("invalid digit '%c' in %s literal", r, name))
+	}
+	return nil
+}
+
+func (in *Input) readBinaryLiteral(col int, val []rune) (*Token, error) {
+	return in.readIntegerLiteral(col, val, isBinaryDigit, "binary")
+}
+
+func (in *Input) readOctalLiteral(col int, val []rune) (*Token, error) {
+	return in.readIntegerLiteral(col, val, isOctalDigit, "octal")
+}
+
+// readIntegerLiteral reads binary and octal literals. The literals
+// with the 0b and 0o prefixes can have a fraction: 0b10.11, 0o10.4.
+// The legacy octal literals without the 'o' are integers.
+func (in *Input) readIntegerLiteral(col int, val []rune,
+	isDigit func(r rune) bool, name string) (*Token, error) {
+
+	val, count, err := in.readDigits(val, isDigit, true)
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 && len(val) > 1 {
+		_, c, _ := in.Rune(false)
+		return nil, NewError(c, fmt.Errorf("%s literal has no digits", name))
+	}
+	r, c, err := in.Rune(false)
+	if err != nil {
+		return nil, NewError(c, err)
+	}
+	if r == '.' && in.peekDigits(1) {
+		if len(val) < 2 || isDecimalDigit(val[1]) {
+			return nil, NewError(c,
+				fmt.Errorf("%s literal with a fraction needs the 0o prefix",
+					name))
+		}
+		val, _, err = in.readDigits(append(val, r), isDigit, false)
+		if err != nil {
+			return nil, err
+		}
+		err = in.checkLiteralEnd(name)
+		if err != nil {
+			return nil, err
+		}
+		f, _, err := big.ParseFloat(string(val), 0, in.config.Precision,
+			in.config.Rounding)
+		if err != nil {
+			return nil, NewError(col, err)
+		}
+		return &Token{
+			Column: col,
+			Type:   TFloat,
+			FloatVal: BigFloatValue{
+				f: f,
+			},
+		}, nil
+	}
+	in.UngetRune(r)
+	err = in.checkLiteralEnd(name)
+	if err != nil {
+		return nil, err
+	}
+	i, ok := new(big.Int).SetString(string(val), 0)
+	if !ok {
+		return nil, NewError(col,
+			fmt.Errorf("invalid integer literal: %s", string(val)))
+	}
+	return in.intLiteral(col, i, true)
+}
+
+// readHexLiteral reads hexadecimal integer and floating point
+// literals. The hexadecimal floating point literals have a fraction,
+// a binary exponent, or both: 0xff.8, 0x1p-2, 0x1.8p3.
+func (in *Input) readHexLiteral(col int, val []rune) (*Token, error) {
+	val, count, err := in.readDigits(val, isHexDigit, true)
+	if err != nil {
+		return nil, err
+	}
+	r, c, err := in.Rune(false)
+	if err != nil {
+		return nil, NewError(c, err)
+	}
+	var isFloat bool
+	if r == '.' {
+		isFloat = true
+		var n int
+		val, n, err = in.readDigits(append(val, r), isHexDigit, false)
+		if err != nil {
+			return nil, err
+		}
+		count += n
+		r, c, err = in.Rune(false)
+		if err != nil {
+			return nil, NewError(c, err)
+		}
+	}
+	if count == 0 {
+		return nil, NewError(c,
+			errors.New("hexadecimal literal has no digits"))
+	}
+	if r == 'p' || r == 'P' {
+		isFloat = true
+		val, err = in.readExponent(append(val, r))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		in.UngetRune(r)
+	}
+	if isFloat {
+		f, _, err := big.ParseFloat(string(val), 0, in.config.Precision,
+			in.config.Rounding)
+		if err != nil {
+			return nil, NewError(col, err)
+		}
+		return &Token{
+			Column: col,
+			Type:   TFloat,
+			FloatVal: BigFloatValue{
+				f: f,
+			},
+		}, nil
+	}
+	i, ok := new(big.Int).SetString(string(val), 0)
+	if !ok {
+		return nil, NewError(col,
+			fmt.Errorf("invalid integer literal: %s", string(val)))
+	}
+	return in.intLiteral(col, i, true)
+}
+
+// readExponent reads the exponent's optional sign and decimal digits
+// into val which ends with the exponent character.
+func (in *Input) readExponent(val []rune) ([]rune, error) {
+	r, c, err := in.Rune(false)
+	if err != nil {
+		return nil, NewError(c, err)
+	}
+	if r == '+' || r == '-' {
+		val = append(val, r)
+	} else {
+		in.UngetRune(r)
+	}
+	val, count, err := in.readDigits(val, isDecimalDigit, false)
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		_, c, _ = in.Rune(false)
+		return nil, NewError(c, errors.New("exponent has no digits"))
+	}
+	return val, nil
+}
+
+// isExponent tests if the input continues with a decimal exponent
+// sign or digit after the exponent character. The function does not
+// consume any input.
+func (in *Input) isExponent() bool {
+	r, _, err := in.Rune(false)
+	if err != nil {
+		return false
+	}
+	in.UngetRune(r)
+	return r == '+' || r == '-' || isDecimalDigit(r)
+}
+
+// readDecimalLiteral reads decimal integer and floating point
+// literals. The val contains the literal runes read so far, using '.'
+// as the decimal separator. The decimal and grouping separators of
+// the input are defined by the input's locale. The grouping
+// separators are accepted only between thousands groups so that
+// numbers can be separated with the grouping separator:
+// f(1,2) calls f with two arguments also in the en locale.
+func (in *Input) readDecimalLiteral(first bool, col int, val []rune) (
+	*Token, error) {
+
+	locale := in.config.Locale
+	var fraction bool
+	var exp []rune
+
+	for _, r := range val {
+		if r == '.' {
+			fraction = true
+		}
+	}
+	prevDigit := isDecimalDigit(val[len(val)-1])
+	var sep bool
+	var sepCol int
+
+	for {
+		r, c, err := in.Rune(first)
+		if err != nil {
+			return nil, NewError(c, err)
+		}
+		if r == '_' {
+			if !prevDigit {
+				return nil, NewError(c,
+					errors.New("'_' must separate successive digits"))
+			}
+			prevDigit = false
+			sep = true
+			sepCol = c
+			continue
+		}
+		if sep && !isDecimalDigit(r) {
+			return nil, NewError(sepCol,
+				errors.New("'_' must separate successive digits"))
+		}
+		sep = false
+
+		if isDecimalDigit(r) {
+			val = append(val, r)
+			prevDigit = true
+			continue
+		}
+		if r == locale.Decimal && !fraction &&
+			(r == '.' || in.peekDigits(1)) {
+			val = append(val, '.')
+			fraction = true
+			prevDigit = false
+			continue
+		}
+		if r == locale.Grouping && r != 0 && !fraction && prevDigit &&
+			in.peekDigits(3) {
+			prevDigit = false
+			continue
+		}
+		if (r == 'e' || r == 'E') && in.isExponent() {
+			exp, err = in.readExponent([]rune{r})
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+		in.UngetRune(r)
+		break
+	}
+
+	if in.readImaginary() {
+		return imaginaryLiteral(col, string(val)+string(exp))
+	}
+	suffix := in.readSuffix()
+	if suffix != nil {
+		return in.scaleLiteral(col, string(val)+string(exp), suffix)
+	}
+	if fraction || len(exp) > 0 {
+		return in.parseFloatLiteral(col, val, exp)
+	}
+	i, ok := new(big.Int).SetString(string(val), 10)
+	if !ok {
+		return nil, NewError(col,
+			fmt.Errorf("invalid integer literal: %s", string(val)))
+	}
+	return in.intLiteral(col, i, false)
+}
+
+// intLiteral creates an integer token of the configured integer
+// type. Binary, octal, and hexadecimal literals can specify the bit
+// pattern of negative signed integers.
+func (in *Input) intLiteral(col int, i *big.Int, pattern bool) (
+	*Token, error) {
+
+	v, err := in.config.IntValue(i, pattern)
+	if err != nil && !pattern {
+		neg, nerr := in.config.IntValue(new(big.Int).Neg(i), false)
+		if nerr == nil {
+			return &Token{
+				Column:    col,
+				Type:      TInteger,
+				StrVal:    i.String(),
+				NegIntVal: neg.(Expr),
+			}, nil
+		}
+	}
+	if err != nil {
+		return nil, NewError(col, err)
+	}
+	return &Token{
+		Column: col,
+		Type:   TInteger,
+		IntVal: v.(Expr),
+	}, nil
+}
+
+// peekDigits tests if the input continues with exactly count decimal
+// digits. The function does not consume any input.
+func (in *Input) peekDigits(count int) bool {
+	var runes []rune
+	defer func() {
+		for i := len(runes) - 1; i >= 0; i-- {
+			in.UngetRune(runes[i])
+		}
+	}()
+	for i := 0; i <= count; i++ {
+		r, _, err := in.Rune(false)
+		if err != nil {
+			return false
+		}
+		runes = append(runes, r)
+		if i < count && !isDecimalDigit(r) {
+			return false
+		}
+	}
+	return count == 1 || !isDecimalDigit(runes[count])
+}
+
+func (in *Input) parseFloatLiteral(col int, val []rune, exp []rune) (
+	*Token, error) {
+
+	f, _, err := big.ParseFloat(string(val)+string(exp), 10,
+		in.config.Precision, in.config.Rounding)
+	if err != nil {
+		return nil, NewError(col, err)
+	}
+	return &Token{
+		Column: col,
+		Type:   TFloat,
+		FloatVal: BigFloatValue{
+			f: f,
+		},
+	}, nil
+}
+
+// UngetToken ungets the token. The next call to GetToken will returns
+// the token instead of consuming input stream.
+func (in *Input) UngetToken(t *Token) {
+	in.ungot = t
+}
+
+// HasRune tests if input is not empty.
+func (in *Input) HasRune() bool {
+	return len(in.line) > 0
+}
+
+// Rune returns the next input rune.
+func (in *Input) Rune(first bool) (rune, int, error) {
+	if len(in.line) == 0 {
+		if in.readline == nil {
+			return 0, in.col, io.ErrUnexpectedEOF
+		}
+		var prompt = in.prompt
+		if !first {
+			prompt = "> "
+		}
+
+		line, err := in.readline.Prompt(prompt)
+		if err != nil {
+			return 0, in.col, err
+		}
+		in.readline.AppendHistory(line)
+		in.line = append([]rune(line), '\n')
+		in.col = len(in.prompt)
+	}
+	r := in.line[0]
+	in.line = in.line[1:]
+	in.col++
+	return r, in.col - 1, nil
+}
+
+// UngetRune returns the argument rune for input. The next call to
+// Rune() will return it instead of consuming input.
+func (in *Input) UngetRune(r rune) {
+	in.line = append([]rune{r}, in.line...)
+	in.col--
+}
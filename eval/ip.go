@@ -0,0 +1,636 @@
+//
+// Copyright (c) 2024 Markku Rossi
+//
+// All rights reserved.
+//
+
+package eval
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+)
+
+var (
+	_ Value = IPValue{}
+	_ Expr  = IPValue{}
+)
+
+// IPValue implements IPv4 and IPv6 addresses and CIDR blocks as
+// Value. The CIDR blocks keep the address as given and they have a
+// prefix length. The addresses have a negative prefix length.
+type IPValue struct {
+	i      *big.Int
+	bits   uint
+	prefix int
+}
+
+// ParseIP parses the IPv4 or IPv6 address. The address can have a
+// prefix length in which case the result is a CIDR block.
+func ParseIP(str string) (IPValue, error) {
+	addr := str
+	prefix := -1
+	idx := strings.IndexByte(str, '/')
+	if idx >= 0 {
+		addr = str[:idx]
+		p := str[idx+1:]
+		if len(p) == 0 || len(p) > 3 || strings.Trim(p, "0123456789") != "" {
+			return IPValue{}, fmt.Errorf("invalid prefix length '%s'", p)
+		}
+		prefix, _ = strconv.Atoi(p)
+	}
+
+	var ip net.IP
+	var bits uint
+	if strings.IndexByte(addr, ':') >= 0 {
+		ip = net.ParseIP(addr)
+		bits = 128
+	} else if isIPv4(addr) {
+		ip = net.ParseIP(addr).To4()
+		bits = 32
+	}
+	if ip == nil {
+		return IPValue{}, fmt.Errorf("invalid IP address '%s'", addr)
+	}
+	if prefix > int(bits) {
+		return IPValue{}, fmt.Errorf("invalid IPv%d prefix length %d",
+			ipVersion(bits), prefix)
+	}
+	return IPValue{
+		i:      new(big.Int).SetBytes(ip),
+		bits:   bits,
+		prefix: prefix,
+	}, nil
+}
+
+// isIPv4 tests if the string is a dotted decimal IPv4 address. The
+// octets can't have leading zeros since they are ambiguous with
+// octal numbers.
+func isIPv4(str string) bool {
+	parts := strings.Split(str, ".")
+	if len(parts) != 4 {
+		return false
+	}
+	for _, part := range parts {
+		if len(part) == 0 || len(part) > 3 ||
+			strings.Trim(part, "0123456789") != "" ||
+			(len(part) > 1 && part[0] == '0') {
+			return false
+		}
+	}
+	return true
+}
+
+func ipVersion(bits uint) int {
+	if bits == 32 {
+		return 4
+	}
+	return 6
+}
+
+func isIPType(t Type) bool {
+	return t == TypeIP || t == TypeCIDR
+}
+
+// newIP creates an address of bits bits from the integer number.
+func newIP(i *big.Int, bits uint) (IPValue, error) {
+	_, max := intRange(bits, false)
+	if i.Sign() < 0 || i.Cmp(max) > 0 {
+		return IPValue{}, fmt.Errorf("IPv%d address out of range",
+			ipVersion(bits))
+	}
+	return IPValue{
+		i:      i,
+		bits:   bits,
+		prefix: -1,
+	}, nil
+}
+
+// ValueIP returns the value as an IP address. Integers are converted
+// to IPv4 addresses if they fit into 32 bits and to IPv6 addresses
+// otherwise.
+func ValueIP(value Value) (IPValue, error) {
+	if v, ok := value.(IPValue); ok {
+		return v, nil
+	}
+	i, err := ValueBigInt(value)
+	if err != nil {
+		return IPValue{},
+			fmt.Errorf("type conversion from %s to ip failed", value.Type())
+	}
+	if i.BitLen() <= 32 {
+		return newIP(i, 32)
+	}
+	return newIP(i, 128)
+}
+
+func (v IPValue) String() string {
+	ip := net.IP(v.Bytes())
+	var str string
+	if v.bits == 128 && ip.To4() != nil {
+		str = "::ffff:" + ip.To4().String()
+	} else {
+		str = ip.String()
+	}
+	if v.prefix >= 0 {
+		str += fmt.Sprintf("/%d", v.prefix)
+	}
+	return str
+}
+
+// Format implements Value.Format(). The addresses are printed in
+// the dotted decimal or colon-separated hexadecimal notation in the
+// base 10 and as integer numbers in other bases.
+func (v IPValue) Format(options Options) string {
+	if options.Encoding != EncodingNone {
+		return options.Encoding.Encode(v.Bytes())
+	}
+	if options.Base == Base10 && !options.String && !options.Human {
+		return v.String()
+	}
+	str := formatInteger(v.i, v.bits, options)
+	if v.prefix >= 0 {
+		str += fmt.Sprintf("/%d", v.prefix)
+	}
+	return str
+}
+
+// Type implements Value.Type().
+func (v IPValue) Type() Type {
+	if v.prefix >= 0 {
+		return TypeCIDR
+	}
+	return TypeIP
+}
+
+// Eval implements Expr.Eval().
+func (v IPValue) Eval(env *Env) (Value, error) {
+	return v, nil
+}
+
+// Int returns the address as *big.Int.
+func (v IPValue) Int() *big.Int {
+	return v.i
+}
+
+// Bits returns the address size in bits: 32 for IPv4 and 128 for
+// IPv6 addresses.
+func (v IPValue) Bits() uint {
+	return v.bits
+}
+
+// Prefix returns the prefix length of the CIDR block. The prefix
+// length of addresses is negative.
+func (v IPValue) Prefix() int {
+	return v.prefix
+}
+
+// Bytes returns the address in the network byte order.
+func (v IPValue) Bytes() []byte {
+	data := v.i.Bytes()
+	result := make([]byte, v.bits/8)
+	copy(result[len(result)-len(data):], data)
+	return result
+}
+
+// Addr returns the address of the CIDR block.
+func (v IPValue) Addr() IPValue {
+	v.prefix = -1
+	return v
+}
+
+// hostBits returns the number of host bits of the CIDR block.
+func (v IPValue) hostBits() uint {
+	if v.prefix < 0 {
+		return 0
+	}
+	return v.bits - uint(v.prefix)
+}
+
+// Netmask returns the network mask of the CIDR block.
+func (v IPValue) Netmask() IPValue {
+	_, all := intRange(v.bits, false)
+	_, host := intRange(v.hostBits(), false)
+	return IPValue{
+		i:      new(big.Int).Xor(all, host),
+		bits:   v.bits,
+		prefix: -1,
+	}
+}
+
+// Network returns the network address of the CIDR block.
+func (v IPValue) Network() IPValue {
+	return IPValue{
+		i:      new(big.Int).And(v.i, v.Netmask().i),
+		bits:   v.bits,
+		prefix: -1,
+	}
+}
+
+// Broadcast returns the broadcast address of the CIDR block. For
+// IPv6 blocks, this is the last address of the block.
+func (v IPValue) Broadcast() IPValue {
+	_, host := intRange(v.hostBits(), false)
+	return IPValue{
+		i:      new(big.Int).Or(v.i, host),
+		bits:   v.bits,
+		prefix: -1,
+	}
+}
+
+// Hosts returns the number of host addresses in the CIDR block. The
+// IPv4 network and broadcast addresses are not host addresses,
+// except in the /31 and /32 blocks.
+func (v IPValue) Hosts() *big.Int {
+	n := new(big.Int).Lsh(bigOne, v.hostBits())
+	if v.bits == 32 && v.hostBits() > 1 {
+		n.Sub(n, big.NewInt(2))
+	}
+	return n
+}
+
+// Contains tests if the CIDR block contains the address or the CIDR
+// block o.
+func (v IPValue) Contains(o IPValue) bool {
+	if v.bits != o.bits || o.prefix >= 0 && o.prefix < v.prefix {
+		return false
+	}
+	return v.Network().i.Cmp(o.Network().i) <= 0 &&
+		v.Broadcast().i.Cmp(o.Network().i) >= 0
+}
+
+// Subnet returns the num:th subnet of the CIDR block when the block
+// is split into subnets that have newbits longer prefix.
+func (v IPValue) Subnet(newbits, num int64) (IPValue, error) {
+	if newbits < 0 || newbits > int64(v.hostBits()) {
+		return IPValue{}, fmt.Errorf("invalid subnet bits %d for %s",
+			newbits, v)
+	}
+	if num < 0 || big.NewInt(num).BitLen() > int(newbits) {
+		return IPValue{}, fmt.Errorf("subnet number %d out of range for %s",
+			num, v)
+	}
+	prefix := v.prefix + int(newbits)
+	offset := new(big.Int).Lsh(big.NewInt(num), v.bits-uint(prefix))
+	return IPValue{
+		i:      offset.Or(offset, v.Network().i),
+		bits:   v.bits,
+		prefix: prefix,
+	}, nil
+}
+
+// add adds the offset to the address. The CIDR blocks are moved by
+// whole blocks so that 10.0.0.0/24 + 1 is 10.0.1.0/24.
+func (v IPValue) add(offset *big.Int) (IPValue, error) {
+	i := v.i
+	if v.prefix >= 0 {
+		i = v.Network().i
+		offset = new(big.Int).Lsh(offset, v.hostBits())
+	}
+	result, err := newIP(new(big.Int).Add(i, offset), v.bits)
+	if err != nil {
+		return IPValue{}, err
+	}
+	result.prefix = v.prefix
+	return result, nil
+}
+
+func (v IPValue) not() IPValue {
+	_, max := intRange(v.bits, false)
+	return IPValue{
+		i:      new(big.Int).Xor(v.i, max),
+		bits:   v.bits,
+		prefix: -1,
+	}
+}
+
+// mask converts the integer number to a bit mask of the address
+// size. Negative numbers specify their two's complement bit pattern.
+func (v IPValue) mask(i *big.Int) (*big.Int, error) {
+	if i.Sign() < 0 {
+		return wrapBigInt(i, v.bits, false), nil
+	}
+	if uint(i.BitLen()) > v.bits {
+		return nil, fmt.Errorf("integer %s overflows IPv%d address",
+			i, ipVersion(v.bits))
+	}
+	return i, nil
+}
+
+// evalIP evaluates the binary operation where at least one of the
+// operands is an IP address or a CIDR block. The addresses support
+// offset arithmetic, bitwise operations with masks, and
+// comparison. The difference of two addresses is an integer.
+func (b binary) evalIP(env *Env, v1, v2 Value) (Value, error) {
+	a1, ok1 := v1.(IPValue)
+	a2, ok2 := v2.(IPValue)
+
+	if ok1 && ok2 {
+		if a1.bits != a2.bits {
+			return nil, NewError(b.col,
+				fmt.Errorf("mixed IPv4 and IPv6 operands for '%s'", b.op))
+		}
+		switch b.op {
+		case TEq, TNeq, '<', '>', TLe, TGe:
+			cmp := a1.i.Cmp(a2.i)
+			if cmp == 0 {
+				cmp = a1.prefix - a2.prefix
+			}
+			return b.compareResult(cmp), nil
+
+		case '-':
+			if a1.prefix < 0 && a2.prefix < 0 {
+				v, err := env.Config().IntValue(new(big.Int).Sub(a1.i, a2.i),
+					false)
+				if err != nil {
+					return nil, NewError(b.col, err)
+				}
+				return v, nil
+			}
+
+		case '&', '|', '^':
+			if a1.prefix < 0 && a2.prefix < 0 {
+				return b.evalIPMask(a1, a2.i)
+			}
+		}
+		return nil, NewError(b.col,
+			fmt.Errorf("unsupport values %s and %s for binary operand '%s'",
+				v1.Type(), v2.Type(), b.op))
+	}
+
+	// One of the operands is an integer.
+	addr, other := a1, v2
+	if ok2 {
+		addr, other = a2, v1
+	}
+	i, err := ValueBigInt(other)
+	if bits, _ := IntBits(other.Type()); err != nil || bits == 0 {
+		return nil, NewError(b.col,
+			fmt.Errorf("unsupport values %s and %s for binary operand '%s'",
+				v1.Type(), v2.Type(), b.op))
+	}
+	switch b.op {
+	case '+', '-':
+		if b.op == '-' {
+			if ok2 {
+				break
+			}
+			i.Neg(i)
+		}
+		result, err := addr.add(i)
+		if err != nil {
+			return nil, NewError(b.col, err)
+		}
+		return result, nil
+
+	case '&', '|', '^':
+		if addr.prefix >= 0 {
+			break
+		}
+		mask, err := addr.mask(i)
+		if err != nil {
+			return nil, NewError(b.col, err)
+		}
+		return b.evalIPMask(addr, mask)
+	}
+	return nil, NewError(b.col,
+		fmt.Errorf("unsupport values %s and %s for binary operand '%s'",
+			v1.Type(), v2.Type(), b.op))
+}
+
+func (b binary) evalIPMask(addr IPValue, mask *big.Int) (Value, error) {
+	result := new(big.Int)
+	switch b.op {
+	case '&':
+		result.And(addr.i, mask)
+	case '|':
+		result.Or(addr.i, mask)
+	default:
+		result.Xor(addr.i, mask)
+	}
+	return IPValue{
+		i:      result,
+		bits:   addr.bits,
+		prefix: -1,
+	}, nil
+}
+
+// isAddressStart tests if the rune can start an IP address literal.
+func isAddressStart(r rune) bool {
+	return r == ':' || isHexDigit(r)
+}
+
+func isAddressRune(r rune) bool {
+	return r == ':' || r == '.' || isHexDigit(r)
+}
+
+// readAddress reads the MAC, IPv4, or IPv6 address literal starting
+// with the rune r. The addresses can have a prefix length which makes them
+// CIDR block literals: 10.0.0.0/8. The function returns a nil token
+// without consuming input if the input does not contain an address
+// literal.
+func (in *Input) readAddress(first bool, col int, r rune) (*Token, error) {
+	t, err := in.readMAC(first, col, r)
+	if t != nil || err != nil {
+		return t, err
+	}
+
+	var n int
+	for n < len(in.line) && isAddressRune(in.line[n]) {
+		n++
+	}
+	lit := string(r) + string(in.line[:n])
+
+	if strings.IndexByte(lit, ':') < 0 {
+		// IPv4 addresses are four decimal numbers. The octets are
+		// validated by ParseIP so that 10.0.0.256 is an invalid
+		// address instead of an invalid float number.
+		parts := strings.Split(lit, ".")
+		if len(parts) != 4 || !isDecimalDigit(r) {
+			return nil, nil
+		}
+		for _, part := range parts {
+			if len(part) == 0 || strings.Trim(part, "0123456789") != "" {
+				return nil, nil
+			}
+		}
+	} else {
+		// The IPv6 address can be followed by the ':' of the
+		// conditional expression.
+		for len(lit) > 1 && net.ParseIP(lit) == nil &&
+			strings.HasSuffix(lit, ":") {
+			lit = lit[:len(lit)-1]
+			n--
+		}
+		if net.ParseIP(lit) == nil {
+			return nil, nil
+		}
+	}
+
+	// Prefix length.
+	if n+1 < len(in.line) && in.line[n] == '/' &&
+		isDecimalDigit(in.line[n+1]) {
+		lit += "/"
+		for n++; n < len(in.line) && isDecimalDigit(in.line[n]); n++ {
+			lit += string(in.line[n])
+		}
+	}
+	for i := 0; i < n; i++ {
+		in.Rune(first)
+	}
+
+	v, err := ParseIP(lit)
+	if err != nil {
+		return nil, NewError(col, err)
+	}
+	return &Token{
+		Column:  col,
+		Type:    TAddress,
+		AddrVal: v,
+	}, nil
+}
+
+// ipArg evaluates the builtin function's idx:th argument as an IP
+// address or a CIDR block.
+func (bi *Builtin) ipArg(env *Env, idx int) (IPValue, error) {
+	v, err := bi.args[idx].Eval(env)
+	if err != nil {
+		return IPValue{}, err
+	}
+	ip, ok := v.(IPValue)
+	if !ok {
+		return IPValue{}, NewError(bi.col,
+			fmt.Errorf("%s: not an IP address: %s", bi.name, v))
+	}
+	return ip, nil
+}
+
+// cidrArg evaluates the builtin function's idx:th argument as a CIDR
+// block.
+func (bi *Builtin) cidrArg(env *Env, idx int) (IPValue, error) {
+	ip, err := bi.ipArg(env, idx)
+	if err != nil {
+		return IPValue{}, err
+	}
+	if ip.prefix < 0 {
+		return IPValue{}, NewError(bi.col,
+			fmt.Errorf("%s: not a CIDR block: %s", bi.name, ip))
+	}
+	return ip, nil
+}
+
+func builtinIP(bi *Builtin, env *Env) (Value, error) {
+	v, err := bi.args[0].Eval(env)
+	if err != nil {
+		return nil, err
+	}
+	var ip IPValue
+	if str, ok := v.(StringValue); ok {
+		ip, err = ParseIP(string(str))
+	} else if len(bi.args) > 1 {
+		var version int64
+		version, err = bi.intArg(env, 1)
+		if err != nil {
+			return nil, err
+		}
+		var i *big.Int
+		i, err = ValueBigInt(v)
+		switch {
+		case err != nil:
+		case version == 4:
+			ip, err = newIP(i, 32)
+		case version == 6:
+			ip, err = newIP(i, 128)
+		default:
+			err = fmt.Errorf("invalid IP version %d", version)
+		}
+	} else {
+		ip, err = ValueIP(v)
+		ip = ip.Addr()
+	}
+	if err != nil {
+		return nil, NewError(bi.col, fmt.Errorf("%s: %s", bi.name, err))
+	}
+	return ip, nil
+}
+
+func builtinNetwork(bi *Builtin, env *Env) (Value, error) {
+	ip, err := bi.cidrArg(env, 0)
+	if err != nil {
+		return nil, err
+	}
+	return ip.Network(), nil
+}
+
+func builtinBroadcast(bi *Builtin, env *Env) (Value, error) {
+	ip, err := bi.cidrArg(env, 0)
+	if err != nil {
+		return nil, err
+	}
+	return ip.Broadcast(), nil
+}
+
+func builtinNetmask(bi *Builtin, env *Env) (Value, error) {
+	ip, err := bi.cidrArg(env, 0)
+	if err != nil {
+		return nil, err
+	}
+	return ip.Netmask(), nil
+}
+
+// builtinHosts returns the number of hosts as an integer of the
+// configured word size. The IPv6 blocks can have more hosts than fit
+// in the integers and their counts are returned as exact rational
+// numbers.
+func builtinHosts(bi *Builtin, env *Env) (Value, error) {
+	ip, err := bi.cidrArg(env, 0)
+	if err != nil {
+		return nil, err
+	}
+	hosts := ip.Hosts()
+	v, err := env.Config().IntValue(hosts, false)
+	if err != nil {
+		return RationalValue{
+			r: new(big.Rat).SetInt(hosts),
+		}, nil
+	}
+	return v, nil
+}
+
+func builtinContains(bi *Builtin, env *Env) (Value, error) {
+	ip, err := bi.cidrArg(env, 0)
+	if err != nil {
+		return nil, err
+	}
+	o, err := bi.ipArg(env, 1)
+	if err != nil {
+		return nil, err
+	}
+	return BoolValue(ip.Contains(o)), nil
+}
+
+func builtinSubnet(bi *Builtin, env *Env) (Value, error) {
+	ip, err := bi.cidrArg(env, 0)
+	if err != nil {
+		return nil, err
+	}
+	newbits, err := bi.intArg(env, 1)
+	if err != nil {
+		return nil, err
+	}
+	var num int64
+	if len(bi.args) > 2 {
+		num, err = bi.intArg(env, 2)
+		if err != nil {
+			return nil, err
+		}
+	}
+	result, err := ip.Subnet(newbits, num)
+	if err != nil {
+		return nil, NewError(bi.col, fmt.Errorf("%s: %s", bi.name, err))
+	}
+	return result, nil
+}
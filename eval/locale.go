@@ -0,0 +1,107 @@
+//
+// Copyright (c) 2024 Markku Rossi
+//
+// All rights reserved.
+//
+
+package eval
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Locale defines the number separators for input and output.
+type Locale struct {
+	Name string
+	// Decimal separates the integer and fraction parts of numbers.
+	Decimal rune
+	// Grouping separates the thousands groups of numbers. The zero
+	// value disables grouping.
+	Grouping rune
+}
+
+func (l *Locale) String() string {
+	return l.Name
+}
+
+// localeC is the default locale. It uses '.' as the decimal separator
+// and does not group digits.
+var localeC = &Locale{
+	Name:    "C",
+	Decimal: '.',
+}
+
+var locales = map[string]*Locale{
+	"C":  localeC,
+	"ch": {Name: "ch", Decimal: '.', Grouping: '\''},
+	"de": {Name: "de", Decimal: ',', Grouping: '.'},
+	"en": {Name: "en", Decimal: '.', Grouping: ','},
+	"es": {Name: "es", Decimal: ',', Grouping: '.'},
+	"fi": {Name: "fi", Decimal: ',', Grouping: ' '},
+	"fr": {Name: "fr", Decimal: ',', Grouping: ' '},
+	"it": {Name: "it", Decimal: ',', Grouping: '.'},
+	"nl": {Name: "nl", Decimal: ',', Grouping: '.'},
+	"sv": {Name: "sv", Decimal: ',', Grouping: ' '},
+}
+
+// LookupLocale returns the locale by its name.
+func LookupLocale(name string) (*Locale, error) {
+	l, ok := locales[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown locale '%s', supported locales: %s",
+			name, strings.Join(LocaleNames(), ", "))
+	}
+	return l, nil
+}
+
+// LocaleNames returns the sorted names of the supported locales.
+func LocaleNames() []string {
+	var names []string
+	for name := range locales {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// localize formats the decimal number string s according to the
+// locale. The number s must use '.' as its decimal separator.
+func (l *Locale) localize(s string) string {
+	if l == nil || (l.Decimal == '.' && l.Grouping == 0) {
+		return s
+	}
+	var sign string
+	if len(s) > 0 && (s[0] == '-' || s[0] == '+') {
+		sign = s[:1]
+		s = s[1:]
+	}
+	integer := s
+	var fraction string
+	idx := strings.IndexByte(s, '.')
+	if idx >= 0 {
+		integer = s[:idx]
+		fraction = s[idx+1:]
+	}
+	for _, r := range integer {
+		if !isDecimalDigit(r) {
+			// Inf, NaN, or exponent notation.
+			return sign + s
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(sign)
+	for i, r := range integer {
+		if i > 0 && l.Grouping != 0 && (len(integer)-i)%3 == 0 {
+			sb.WriteRune(l.Grouping)
+		}
+		sb.WriteRune(r)
+	}
+	if idx >= 0 {
+		sb.WriteRune(l.Decimal)
+		sb.WriteString(fraction)
+	}
+	return sb.String()
+}
@@ -0,0 +1,442 @@
+//
+// Copyright (c) 2024 Markku Rossi
+//
+// All rights reserved.
+//
+
+package eval
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+var (
+	_ Value = MACValue{}
+	_ Expr  = MACValue{}
+)
+
+// MACValue implements EUI-48 MAC addresses and EUI-64 identifiers
+// as Value.
+type MACValue struct {
+	i    uint64
+	bits uint
+}
+
+// MACFormat defines the output notation of MAC addresses.
+type MACFormat int
+
+// MAC address notations.
+const (
+	MACColon MACFormat = iota
+	MACDash
+	MACDot
+	MACBare
+)
+
+// ParseMAC parses the MAC address in the colon, dash, or dot
+// notation: 00:1a:2b:3c:4d:5e, 00-1a-2b-3c-4d-5e, or 001a.2b3c.4d5e.
+// The addresses with eight octets are EUI-64 identifiers.
+func ParseMAC(str string) (MACValue, error) {
+	var sep string
+	var digits int
+	switch {
+	case strings.IndexByte(str, ':') >= 0:
+		sep, digits = ":", 2
+	case strings.IndexByte(str, '-') >= 0:
+		sep, digits = "-", 2
+	default:
+		sep, digits = ".", 4
+	}
+	parts := strings.Split(str, sep)
+	bits := uint(len(parts) * digits * 4)
+	if bits != 48 && bits != 64 {
+		return MACValue{}, fmt.Errorf("invalid MAC address '%s'", str)
+	}
+	var i uint64
+	for _, part := range parts {
+		if len(part) != digits {
+			return MACValue{}, fmt.Errorf("invalid MAC address '%s'", str)
+		}
+		for _, r := range part {
+			if !isHexDigit(r) {
+				return MACValue{},
+					fmt.Errorf("invalid MAC address '%s'", str)
+			}
+			i = i<<4 | uint64(hexDigit(r))
+		}
+	}
+	return MACValue{
+		i:    i,
+		bits: bits,
+	}, nil
+}
+
+func hexDigit(r rune) int {
+	switch {
+	case '0' <= r && r <= '9':
+		return int(r - '0')
+	case 'a' <= r && r <= 'f':
+		return int(r - 'a' + 10)
+	default:
+		return int(r - 'A' + 10)
+	}
+}
+
+// newMAC creates a MAC address of bits bits from the integer number.
+func newMAC(i *big.Int, bits uint) (MACValue, error) {
+	if i.Sign() < 0 || uint(i.BitLen()) > bits {
+		return MACValue{}, fmt.Errorf("EUI-%d address out of range", bits)
+	}
+	return MACValue{
+		i:    i.Uint64(),
+		bits: bits,
+	}, nil
+}
+
+// ValueMAC returns the value as a MAC address. Integers are converted
+// to EUI-48 addresses if they fit into 48 bits and to EUI-64
+// identifiers otherwise.
+func ValueMAC(value Value) (MACValue, error) {
+	if v, ok := value.(MACValue); ok {
+		return v, nil
+	}
+	i, err := ValueBigInt(value)
+	if err != nil {
+		return MACValue{},
+			fmt.Errorf("type conversion from %s to mac failed", value.Type())
+	}
+	if i.BitLen() <= 48 {
+		return newMAC(i, 48)
+	}
+	return newMAC(i, 64)
+}
+
+func (v MACValue) String() string {
+	return v.Notation(MACColon)
+}
+
+// Notation returns the MAC address in the notation.
+func (v MACValue) Notation(format MACFormat) string {
+	hex := fmt.Sprintf("%0*x", v.bits/4, v.i)
+	var sep string
+	var digits int
+	switch format {
+	case MACDash:
+		sep, digits = "-", 2
+	case MACDot:
+		sep, digits = ".", 4
+	case MACBare:
+		return hex
+	default:
+		sep, digits = ":", 2
+	}
+	var parts []string
+	for i := 0; i < len(hex); i += digits {
+		parts = append(parts, hex[i:i+digits])
+	}
+	return strings.Join(parts, sep)
+}
+
+// Format implements Value.Format(). The addresses are printed in the
+// MAC notation of the options in the base 10 and as integer numbers
+// in other bases.
+func (v MACValue) Format(options Options) string {
+	if options.Encoding != EncodingNone {
+		return options.Encoding.Encode(v.Bytes())
+	}
+	if options.Base == Base10 && !options.String && !options.Human {
+		return v.Notation(options.MAC)
+	}
+	return formatInteger(v.Int(), v.bits, options)
+}
+
+// Type implements Value.Type().
+func (v MACValue) Type() Type {
+	return TypeMAC
+}
+
+// Eval implements Expr.Eval().
+func (v MACValue) Eval(env *Env) (Value, error) {
+	return v, nil
+}
+
+// Int returns the address as *big.Int.
+func (v MACValue) Int() *big.Int {
+	return new(big.Int).SetUint64(v.i)
+}
+
+// Bits returns the address size in bits: 48 for EUI-48 addresses and
+// 64 for EUI-64 identifiers.
+func (v MACValue) Bits() uint {
+	return v.bits
+}
+
+// Bytes returns the address octets in the transmission order.
+func (v MACValue) Bytes() []byte {
+	result := make([]byte, v.bits/8)
+	for i := range result {
+		result[i] = byte(v.i >> (v.bits - 8 - uint(i)*8))
+	}
+	return result
+}
+
+// OUI returns the Organizationally Unique Identifier of the address
+// i.e. its first three octets.
+func (v MACValue) OUI() uint32 {
+	return uint32(v.i >> (v.bits - 24))
+}
+
+// Multicast tests if the individual/group bit of the address is set.
+func (v MACValue) Multicast() bool {
+	return v.Bytes()[0]&0x01 != 0
+}
+
+// Local tests if the universal/local bit of the address is set.
+func (v MACValue) Local() bool {
+	return v.Bytes()[0]&0x02 != 0
+}
+
+// EUI64 converts the EUI-48 address to the modified EUI-64 interface
+// identifier of IPv6 addresses: the octets ff:fe are inserted in the
+// middle of the address and the universal/local bit is inverted.
+func (v MACValue) EUI64() (MACValue, error) {
+	if v.bits != 48 {
+		return MACValue{}, fmt.Errorf("%s is not an EUI-48 address", v)
+	}
+	i := v.i>>24<<40 | 0xfffe<<24 | v.i&0xffffff
+	return MACValue{
+		i:    i ^ 0x02<<56,
+		bits: 64,
+	}, nil
+}
+
+// LinkLocal returns the IPv6 link-local address fe80::/64 with the
+// modified EUI-64 interface identifier of the EUI-48 address.
+func (v MACValue) LinkLocal() (IPValue, error) {
+	id, err := v.EUI64()
+	if err != nil {
+		return IPValue{}, err
+	}
+	i := new(big.Int).Lsh(big.NewInt(0xfe80), 112)
+	return IPValue{
+		i:      i.Or(i, id.Int()),
+		bits:   128,
+		prefix: -1,
+	}, nil
+}
+
+func (v MACValue) not() MACValue {
+	return MACValue{
+		i:    ^v.i & (1<<v.bits - 1),
+		bits: v.bits,
+	}
+}
+
+// evalMAC evaluates the binary operation where at least one of the
+// operands is a MAC address. The addresses support offset
+// arithmetic, bitwise operations with masks, and comparison. The
+// difference of two addresses is an integer.
+func (b binary) evalMAC(env *Env, v1, v2 Value) (Value, error) {
+	m1, ok1 := v1.(MACValue)
+	m2, ok2 := v2.(MACValue)
+
+	if ok1 && ok2 {
+		if m1.bits != m2.bits {
+			return nil, NewError(b.col,
+				fmt.Errorf("mixed EUI-48 and EUI-64 operands for '%s'", b.op))
+		}
+		switch b.op {
+		case TEq, TNeq, '<', '>', TLe, TGe:
+			return b.compareResult(m1.Int().Cmp(m2.Int())), nil
+
+		case '-':
+			v, err := env.Config().IntValue(new(big.Int).Sub(m1.Int(),
+				m2.Int()), false)
+			if err != nil {
+				return nil, NewError(b.col, err)
+			}
+			return v, nil
+
+		case '&', '|', '^':
+			return b.evalMACMask(m1, m2.i), nil
+		}
+		return nil, NewError(b.col,
+			fmt.Errorf("unsupport values %s and %s for binary operand '%s'",
+				v1.Type(), v2.Type(), b.op))
+	}
+
+	// One of the operands is an integer.
+	mac, other := m1, v2
+	if ok2 {
+		mac, other = m2, v1
+	}
+	i, err := ValueBigInt(other)
+	bits, _ := IntBits(other.Type())
+	if err != nil || bits == 0 || (b.op == '-' && ok2) {
+		return nil, NewError(b.col,
+			fmt.Errorf("unsupport values %s and %s for binary operand '%s'",
+				v1.Type(), v2.Type(), b.op))
+	}
+	switch b.op {
+	case '+', '-':
+		if b.op == '-' {
+			i.Neg(i)
+		}
+		result, err := newMAC(i.Add(i, mac.Int()), mac.bits)
+		if err != nil {
+			return nil, NewError(b.col, err)
+		}
+		return result, nil
+
+	case '&', '|', '^':
+		if i.Sign() < 0 {
+			i = wrapBigInt(i, mac.bits, false)
+		} else if uint(i.BitLen()) > mac.bits {
+			return nil, NewError(b.col,
+				fmt.Errorf("integer %s overflows EUI-%d address",
+					i, mac.bits))
+		}
+		return b.evalMACMask(mac, i.Uint64()), nil
+	}
+	return nil, NewError(b.col,
+		fmt.Errorf("unsupport values %s and %s for binary operand '%s'",
+			v1.Type(), v2.Type(), b.op))
+}
+
+func (b binary) evalMACMask(mac MACValue, mask uint64) Value {
+	switch b.op {
+	case '&':
+		mac.i &= mask
+	case '|':
+		mac.i |= mask
+	default:
+		mac.i ^= mask
+	}
+	return mac
+}
+
+// readMAC reads the EUI-48 MAC address literal starting with the
+// rune r. The function returns a nil token without consuming input
+// if the input does not contain a MAC address literal.
+func (in *Input) readMAC(first bool, col int, r rune) (*Token, error) {
+	for _, l := range []int{17, 14} {
+		if len(in.line) < l {
+			continue
+		}
+		lit := string(r) + string(in.line[:l-1])
+		if len(in.line) > l-1 {
+			next := in.line[l-1]
+			if isAddressRune(next) || next == '-' || next == '_' ||
+				unicode.IsLetter(next) || unicode.IsDigit(next) {
+				continue
+			}
+		}
+		if l == 17 && lit[2] != ':' && lit[2] != '-' {
+			continue
+		}
+		v, err := ParseMAC(lit)
+		if err != nil || v.bits != 48 {
+			continue
+		}
+		// The dash notation with decimal digits only could also be
+		// a chain of subtractions: 10-20-30-40-50-60.
+		if l == 17 && lit[2] == '-' &&
+			!strings.ContainsAny(lit, "abcdefABCDEF") {
+			return nil, NewError(col,
+				fmt.Errorf("ambiguous MAC address '%s', use mac(\"%s\") "+
+					"or spaces around the subtractions", lit, lit))
+		}
+		for i := 0; i < l-1; i++ {
+			in.Rune(first)
+		}
+		return &Token{
+			Column:  col,
+			Type:    TAddress,
+			AddrVal: v,
+		}, nil
+	}
+	return nil, nil
+}
+
+func builtinMAC(bi *Builtin, env *Env) (Value, error) {
+	v, err := bi.args[0].Eval(env)
+	if err != nil {
+		return nil, err
+	}
+	var mac MACValue
+	if str, ok := v.(StringValue); ok {
+		mac, err = ParseMAC(string(str))
+	} else {
+		mac, err = ValueMAC(v)
+	}
+	if err != nil {
+		return nil, NewError(bi.col, fmt.Errorf("%s: %s", bi.name, err))
+	}
+	return mac, nil
+}
+
+// macArg evaluates the builtin function's idx:th argument as a MAC
+// address.
+func (bi *Builtin) macArg(env *Env, idx int) (MACValue, error) {
+	v, err := bi.args[idx].Eval(env)
+	if err != nil {
+		return MACValue{}, err
+	}
+	mac, ok := v.(MACValue)
+	if !ok {
+		return MACValue{}, NewError(bi.col,
+			fmt.Errorf("%s: not a MAC address: %s", bi.name, v))
+	}
+	return mac, nil
+}
+
+func builtinEUI64(bi *Builtin, env *Env) (Value, error) {
+	mac, err := bi.macArg(env, 0)
+	if err != nil {
+		return nil, err
+	}
+	result, err := mac.EUI64()
+	if err != nil {
+		return nil, NewError(bi.col, fmt.Errorf("%s: %s", bi.name, err))
+	}
+	return result, nil
+}
+
+func builtinLinkLocal(bi *Builtin, env *Env) (Value, error) {
+	mac, err := bi.macArg(env, 0)
+	if err != nil {
+		return nil, err
+	}
+	result, err := mac.LinkLocal()
+	if err != nil {
+		return nil, NewError(bi.col, fmt.Errorf("%s: %s", bi.name, err))
+	}
+	return result, nil
+}
+
+func builtinOUI(bi *Builtin, env *Env) (Value, error) {
+	mac, err := bi.macArg(env, 0)
+	if err != nil {
+		return nil, err
+	}
+	return bi.intResult(env, big.NewInt(int64(mac.OUI())), true)
+}
+
+func builtinMulticast(bi *Builtin, env *Env) (Value, error) {
+	mac, err := bi.macArg(env, 0)
+	if err != nil {
+		return nil, err
+	}
+	return BoolValue(mac.Multicast()), nil
+}
+
+func builtinLocal(bi *Builtin, env *Env) (Value, error) {
+	mac, err := bi.macArg(env, 0)
+	if err != nil {
+		return nil, err
+	}
+	return BoolValue(mac.Local()), nil
+}
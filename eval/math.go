@@ -0,0 +1,294 @@
+//
+// Copyright (c) 2024 Markku Rossi
+//
+// All rights reserved.
+//
+
+package eval
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"math/cmplx"
+)
+
+var (
+	bigOne = big.NewInt(1)
+)
+
+// intPow computes x**y exactly for signed integers of the argument
+// bit size. It returns an error if the result overflows the size.
+// Negative exponents truncate the result towards zero as integer
+// division does.
+func intPow(x, y int64, bits uint) (int64, error) {
+	if y < 0 {
+		switch x {
+		case 0:
+			return 0, errDivideByZero
+		case 1:
+			return 1, nil
+		case -1:
+			if y%2 == 0 {
+				return 1, nil
+			}
+			return -1, nil
+		default:
+			return 0, nil
+		}
+	}
+	if (x >= 2 || x <= -2) && y >= int64(bits) {
+		return 0, fmt.Errorf("integer overflow in %d**%d", x, y)
+	}
+	result := new(big.Int).Exp(big.NewInt(x), big.NewInt(y), nil)
+	if result.BitLen() >= int(bits) {
+		// The minimum value -2^(bits-1) has bit length bits.
+		min := new(big.Int).Lsh(bigOne, bits-1)
+		min.Neg(min)
+		if result.Cmp(min) != 0 {
+			return 0, fmt.Errorf("integer overflow in %d**%d", x, y)
+		}
+	}
+	return result.Int64(), nil
+}
+
+// bigIntPow computes x**y for integers of the word size bits. It
+// returns an error if the result overflows the integer type.
+func bigIntPow(x, y *big.Int, bits uint, signed bool) (*big.Int, error) {
+	if y.Sign() < 0 || x.CmpAbs(bigOne) <= 0 {
+		switch {
+		case x.Sign() == 0:
+			if y.Sign() < 0 {
+				return nil, errDivideByZero
+			}
+			if y.Sign() == 0 {
+				return big.NewInt(1), nil
+			}
+			return big.NewInt(0), nil
+		case x.Cmp(bigOne) == 0:
+			return big.NewInt(1), nil
+		case x.CmpAbs(bigOne) == 0:
+			if y.Bit(0) == 0 {
+				return big.NewInt(1), nil
+			}
+			return big.NewInt(-1), nil
+		default:
+			return big.NewInt(0), nil
+		}
+	}
+	min, max := intRange(bits, signed)
+	if y.Cmp(big.NewInt(int64(bits))) > 0 {
+		return nil, fmt.Errorf("integer overflow in %s**%s", x, y)
+	}
+	result := new(big.Int).Exp(x, y, nil)
+	if result.Cmp(min) < 0 || result.Cmp(max) > 0 {
+		return nil, fmt.Errorf("integer overflow in %s**%s", x, y)
+	}
+	return result, nil
+}
+
+// ratPow computes x**n exactly. It returns an error if x is zero and
+// n is negative.
+func ratPow(x *big.Rat, n int64) (*big.Rat, error) {
+	if n < -maxRatExp || n > maxRatExp {
+		return nil, fmt.Errorf("exponent %d out of range", n)
+	}
+	if n < 0 {
+		if x.Sign() == 0 {
+			return nil, errDivideByZero
+		}
+		x = new(big.Rat).Inv(x)
+		n = -n
+	}
+	e := big.NewInt(n)
+	return new(big.Rat).SetFrac(new(big.Int).Exp(x.Num(), e, nil),
+		new(big.Int).Exp(x.Denom(), e, nil)), nil
+}
+
+// complexPow computes x**y. Integer exponents are computed with
+// repeated multiplication so that, for example, 1i**2 is exactly -1.
+func complexPow(x, y complex128) complex128 {
+	n := real(y)
+	if imag(y) != 0 || n != math.Trunc(n) || math.Abs(n) > maxRatExp {
+		return cmplx.Pow(x, y)
+	}
+	result := complex(1, 0)
+	for e := int64(math.Abs(n)); e > 0; e >>= 1 {
+		if e&1 != 0 {
+			result *= x
+		}
+		x *= x
+	}
+	if n < 0 {
+		return 1 / result
+	}
+	return result
+}
+
+// maxRatExp limits the rational exponents to keep the exact results
+// reasonably sized.
+const maxRatExp = 1 << 16
+
+// bigFloatPow computes x**y with the precision prec.
+func bigFloatPow(x, y *big.Float, prec uint) (*big.Float, error) {
+	if y.IsInt() && y.MinPrec() <= 63 {
+		n, _ := y.Int64()
+		return bigFloatPowInt(x, n, prec), nil
+	}
+	switch x.Sign() {
+	case -1:
+		return nil, fmt.Errorf("negative base %s with fractional exponent %s",
+			x.Text('g', 10), y.Text('g', 10))
+	case 0:
+		if y.Sign() < 0 {
+			return new(big.Float).SetPrec(prec).SetInf(false), nil
+		}
+		return new(big.Float).SetPrec(prec), nil
+	}
+	if x.IsInf() || y.IsInf() {
+		xf, _ := x.Float64()
+		yf, _ := y.Float64()
+		return new(big.Float).SetPrec(prec).SetFloat64(math.Pow(xf, yf)), nil
+	}
+
+	// x**y = exp(y*ln(x))
+	wprec := prec + 64
+	l, err := bigLog(x, wprec)
+	if err != nil {
+		return nil, err
+	}
+	l.Mul(l, y)
+	result, err := bigExp(l, wprec)
+	if err != nil {
+		return nil, err
+	}
+	return result.SetPrec(prec), nil
+}
+
+// bigFloatPowInt computes x**n by repeated squaring.
+func bigFloatPowInt(x *big.Float, n int64, prec uint) *big.Float {
+	neg := n < 0
+	if neg {
+		n = -n
+	}
+	wprec := prec + 64
+
+	result := new(big.Float).SetPrec(wprec).SetInt64(1)
+	base := new(big.Float).SetPrec(wprec).Set(x)
+	for n > 0 {
+		if n&1 == 1 {
+			result.Mul(result, base)
+		}
+		n >>= 1
+		if n > 0 {
+			base.Mul(base, base)
+		}
+	}
+	if neg {
+		if result.Sign() == 0 {
+			return result.SetPrec(prec).SetInf(x.Signbit())
+		}
+		result.Quo(new(big.Float).SetPrec(wprec).SetInt64(1), result)
+	}
+	return result.SetPrec(prec)
+}
+
+// bigAtanh computes atanh(z) = z + z^3/3 + z^5/5 + ... for |z| < 1.
+func bigAtanh(z *big.Float, prec uint) *big.Float {
+	sum := new(big.Float).SetPrec(prec).Set(z)
+	z2 := new(big.Float).SetPrec(prec).Mul(z, z)
+	pow := new(big.Float).SetPrec(prec).Set(z)
+	term := new(big.Float).SetPrec(prec)
+	div := new(big.Float).SetPrec(prec)
+
+	limit := -int(prec) - 2
+	for n := int64(3); ; n += 2 {
+		pow.Mul(pow, z2)
+		term.Quo(pow, div.SetInt64(n))
+		if term.Sign() == 0 || term.MantExp(nil)-sum.MantExp(nil) < limit {
+			break
+		}
+		sum.Add(sum, term)
+	}
+	return sum
+}
+
+// bigLn2 computes ln(2) = 2*atanh(1/3).
+func bigLn2(prec uint) *big.Float {
+	third := new(big.Float).SetPrec(prec).SetInt64(1)
+	third.Quo(third, new(big.Float).SetPrec(prec).SetInt64(3))
+	result := bigAtanh(third, prec)
+	return result.Mul(result, new(big.Float).SetPrec(prec).SetInt64(2))
+}
+
+// bigLog computes the natural logarithm of x with the precision prec.
+func bigLog(x *big.Float, prec uint) (*big.Float, error) {
+	if x.Sign() <= 0 {
+		return nil, fmt.Errorf("logarithm of non-positive value %s",
+			x.Text('g', 10))
+	}
+	wprec := prec + 32
+
+	// x = m * 2**e, 0.5 <= m < 1, ln(x) = ln(m) + e*ln(2).
+	m := new(big.Float).SetPrec(wprec)
+	e := x.MantExp(m)
+
+	// ln(m) = 2*atanh((m-1)/(m+1))
+	num := new(big.Float).SetPrec(wprec).Sub(m, big.NewFloat(1))
+	den := new(big.Float).SetPrec(wprec).Add(m, big.NewFloat(1))
+	z := num.Quo(num, den)
+	result := bigAtanh(z, wprec)
+	result.Mul(result, new(big.Float).SetPrec(wprec).SetInt64(2))
+
+	if e != 0 {
+		ln2 := bigLn2(wprec)
+		ln2.Mul(ln2, new(big.Float).SetPrec(wprec).SetInt64(int64(e)))
+		result.Add(result, ln2)
+	}
+	return result.SetPrec(prec), nil
+}
+
+// bigExp computes e**x with the precision prec.
+func bigExp(x *big.Float, prec uint) (*big.Float, error) {
+	wprec := prec + 64
+
+	// x = k*ln(2) + r, 0 <= r < ln(2), e**x = 2**k * e**r.
+	ln2 := bigLn2(wprec)
+	kf := new(big.Float).SetPrec(wprec).Quo(x, ln2)
+	ki, _ := kf.Int(nil)
+	if x.Sign() < 0 {
+		ki.Sub(ki, bigOne)
+	}
+	if !ki.IsInt64() || ki.Int64() > math.MaxInt32 ||
+		ki.Int64() < math.MinInt32 {
+		return nil, fmt.Errorf("exponent overflow in exp(%s)", x.Text('g', 10))
+	}
+	k := ki.Int64()
+	r := new(big.Float).SetPrec(wprec).SetInt64(k)
+	r.Mul(r, ln2)
+	r.Sub(x, r)
+
+	// Halve r to speed up the Taylor series convergence and square
+	// the result back.
+	const halvings = 16
+	r.SetMantExp(r, -halvings)
+
+	sum := new(big.Float).SetPrec(wprec).SetInt64(1)
+	term := new(big.Float).SetPrec(wprec).SetInt64(1)
+	div := new(big.Float).SetPrec(wprec)
+	limit := -int(wprec) - 2
+	for n := int64(1); ; n++ {
+		term.Mul(term, r)
+		term.Quo(term, div.SetInt64(n))
+		if term.Sign() == 0 || term.MantExp(nil) < limit {
+			break
+		}
+		sum.Add(sum, term)
+	}
+	for i := 0; i < halvings; i++ {
+		sum.Mul(sum, sum)
+	}
+	sum.SetMantExp(sum, int(k))
+
+	return sum.SetPrec(prec), nil
+}
@@ -0,0 +1,254 @@
+//
+// Copyright (c) 2024 Markku Rossi
+//
+// All rights reserved.
+//
+
+package eval
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+var (
+	_ Value = RationalValue{}
+	_ Expr  = RationalValue{}
+)
+
+// RationalFormat defines how rational numbers are printed.
+type RationalFormat int
+
+// Rational number formats.
+const (
+	RationalFraction RationalFormat = iota
+	RationalMixed
+	RationalDecimal
+)
+
+var rationalFormats = map[RationalFormat]string{
+	RationalFraction: "fraction",
+	RationalMixed:    "mixed",
+	RationalDecimal:  "decimal",
+}
+
+func (f RationalFormat) String() string {
+	name, ok := rationalFormats[f]
+	if ok {
+		return name
+	}
+	return fmt.Sprintf("{RationalFormat %d}", f)
+}
+
+// LookupRationalFormat returns the rational number format by its
+// name.
+func LookupRationalFormat(name string) (RationalFormat, error) {
+	for f, n := range rationalFormats {
+		if n == name {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown rational format '%s', supported formats: %s",
+		name, "fraction, mixed, decimal")
+}
+
+// RationalValue implements exact rational numbers as Value.
+type RationalValue struct {
+	r *big.Rat
+}
+
+// NewRationalValue creates a new RationalValue for the argument
+// number.
+func NewRationalValue(r *big.Rat) RationalValue {
+	return RationalValue{
+		r: r,
+	}
+}
+
+// Rat returns the value as *big.Rat.
+func (v RationalValue) Rat() *big.Rat {
+	return v.r
+}
+
+func (v RationalValue) String() string {
+	return v.r.RatString()
+}
+
+// Format implements Value.Format().
+func (v RationalValue) Format(options Options) string {
+	if options.String {
+		i := new(big.Int).Quo(v.r.Num(), v.r.Denom())
+		return stringify(i.Int64(), options.Base)
+	}
+	if options.Human {
+		f, _ := v.r.Float64()
+		return formatHumanNumber(f, options)
+	}
+	if options.Base != Base10 {
+		str := formatBigInt(v.r.Num(), options)
+		if !v.r.IsInt() {
+			str += "/" + formatBigInt(v.r.Denom(), options)
+		}
+		return str
+	}
+
+	switch options.Rational {
+	case RationalMixed:
+		if v.r.IsInt() {
+			return formatBigInt(v.r.Num(), options)
+		}
+		i, rem := new(big.Int).QuoRem(v.r.Num(), v.r.Denom(), new(big.Int))
+		if i.Sign() == 0 {
+			return formatBigInt(v.r.Num(), options) + "/" +
+				formatBigInt(v.r.Denom(), options)
+		}
+		return formatBigInt(i, options) + " " +
+			formatBigInt(rem.Abs(rem), options) + "/" +
+			formatBigInt(v.r.Denom(), options)
+
+	case RationalDecimal:
+		digits := options.Digits
+		if digits <= 0 {
+			var exact bool
+			digits, exact = decimalDigits(v.r.Denom())
+			if !exact {
+				digits = 20
+			}
+		}
+		return options.Locale.localize(formatRatDigits(v.r, digits,
+			options.Rounding))
+
+	default:
+		str := formatBigInt(v.r.Num(), options)
+		if !v.r.IsInt() {
+			str += "/" + formatBigInt(v.r.Denom(), options)
+		}
+		return str
+	}
+}
+
+// Type implements Value.Type().
+func (v RationalValue) Type() Type {
+	return TypeRational
+}
+
+// Eval implements Expr.Eval().
+func (v RationalValue) Eval(env *Env) (Value, error) {
+	return v, nil
+}
+
+// formatBigInt formats the integer number according to the options.
+func formatBigInt(i *big.Int, options Options) string {
+	str := i.Text(options.Base.Base())
+	var sign string
+	if strings.HasPrefix(str, "-") {
+		sign = "-"
+		str = str[1:]
+	}
+	if options.Base == Base10 {
+		str = options.Locale.localize(str)
+	}
+	return sign + options.Base.Prefix() + str
+}
+
+// decimalDigits returns the number of fraction digits needed to print
+// a rational number with the denominator as an exact decimal
+// number. The exact result is false if the decimal expansion does not
+// terminate.
+func decimalDigits(denom *big.Int) (int, bool) {
+	d := new(big.Int).Set(denom)
+	m := new(big.Int)
+	var twos, fives int
+	for {
+		q, r := new(big.Int).QuoRem(d, big.NewInt(2), m)
+		if r.Sign() != 0 {
+			break
+		}
+		d = q
+		twos++
+	}
+	for {
+		q, r := new(big.Int).QuoRem(d, big.NewInt(5), m)
+		if r.Sign() != 0 {
+			break
+		}
+		d = q
+		fives++
+	}
+	if d.Cmp(bigOne) != 0 {
+		return 0, false
+	}
+	if twos > fives {
+		return twos, true
+	}
+	return fives, true
+}
+
+// ratMod returns the remainder x - y*trunc(x/y). The divisor y must
+// be non-zero.
+func ratMod(x, y *big.Rat) *big.Rat {
+	q := new(big.Rat).Quo(x, y)
+	q.SetInt(new(big.Int).Quo(q.Num(), q.Denom()))
+	return q.Sub(x, q.Mul(q, y))
+}
+
+// formatRatDigits formats the rational number as a decimal number
+// with digits fraction digits. The last digit is rounded according to
+// the rounding mode.
+func formatRatDigits(r *big.Rat, digits int, mode big.RoundingMode) string {
+	return formatRatBase(r, 10, digits, mode)
+}
+
+// formatRatBase formats the rational number in the base with digits
+// fraction digits. The last digit is rounded according to the
+// rounding mode.
+func formatRatBase(r *big.Rat, base, digits int,
+	mode big.RoundingMode) string {
+
+	scale := new(big.Int).Exp(big.NewInt(int64(base)),
+		big.NewInt(int64(digits)), nil)
+	q := roundRat(new(big.Rat).Mul(r, new(big.Rat).SetInt(scale)), mode)
+
+	var neg string
+	if q.Sign() < 0 {
+		neg = "-"
+		q.Neg(q)
+	}
+	str := q.Text(base)
+	if digits <= 0 {
+		return neg + str
+	}
+	if len(str) <= digits {
+		str = strings.Repeat("0", digits-len(str)+1) + str
+	}
+	return neg + str[:len(str)-digits] + "." + str[len(str)-digits:]
+}
+
+// roundRat rounds the rational number to an integer according to the
+// rounding mode.
+func roundRat(r *big.Rat, mode big.RoundingMode) *big.Int {
+	q, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if rem.Sign() == 0 {
+		return q
+	}
+	sign := int64(r.Sign())
+	var up bool
+	switch mode {
+	case big.ToZero:
+	case big.AwayFromZero:
+		up = true
+	case big.ToNegativeInf:
+		up = sign < 0
+	case big.ToPositiveInf:
+		up = sign > 0
+	default:
+		cmp := new(big.Int).Lsh(rem.Abs(rem), 1).Cmp(r.Denom())
+		up = cmp > 0 || (cmp == 0 &&
+			(mode == big.ToNearestAway || q.Bit(0) == 1))
+	}
+	if up {
+		q.Add(q, big.NewInt(sign))
+	}
+	return q
+}
@@ -1,10 +1,10 @@
 //
-// Copyright (c) 2020 Markku Rossi
+// Copyright (c) 2020, 2024 Markku Rossi
 //
 // All rights reserved.
 //
 
-package main
+package eval
 
 // Readline implements line-based user input.
 type Readline interface {
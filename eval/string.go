@@ -0,0 +1,215 @@
+//
+// Copyright (c) 2024 Markku Rossi
+//
+// All rights reserved.
+//
+
+package eval
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	_ Value = StringValue("")
+	_ Expr  = StringValue("")
+)
+
+// StringValue implements string values as Value. The strings are
+// byte sequences which can hold arbitrary bytes. The len, substr, and
+// index builtins count runes and fail for strings which are not valid
+// UTF-8.
+type StringValue string
+
+func (v StringValue) String() string {
+	return string(v)
+}
+
+// Format implements Value.Format().
+func (v StringValue) Format(options Options) string {
+	return options.Encoding.Encode([]byte(v))
+}
+
+// Type implements Value.Type().
+func (v StringValue) Type() Type {
+	return TypeString
+}
+
+// Eval implements Expr.Eval().
+func (v StringValue) Eval(env *Env) (Value, error) {
+	return v, nil
+}
+
+// readEscape reads the character escape following the backslash in
+// the char or string literal. The quote is the literal's quote
+// character which can also be escaped. The escapes are the Go
+// rune literal escapes: the single character escapes, \xhh, \ooo,
+// \uhhhh, and \Uhhhhhhhh. The octal escapes can also have less than
+// three digits so \0 is the NUL character. The byte result is true
+// if the escape specifies a byte value with a hexadecimal or octal
+// escape.
+func (in *Input) readEscape(first bool, quote rune, name string) (
+	r rune, isByte bool, err error) {
+
+	ch, col, err := in.Rune(first)
+	if err != nil {
+		return 0, false, NewError(col, err)
+	}
+	switch ch {
+	case 'a':
+		return '\a', false, nil
+	case 'b':
+		return '\b', false, nil
+	case 'f':
+		return '\f', false, nil
+	case 'n':
+		return '\n', false, nil
+	case 'r':
+		return '\r', false, nil
+	case 't':
+		return '\t', false, nil
+	case 'v':
+		return '\v', false, nil
+	case '\\':
+		return '\\', false, nil
+	case quote:
+		return quote, false, nil
+
+	case 'x':
+		r, err = in.readEscapeDigits(first, 16, 2, 2, name)
+		return r, true, err
+
+	case 'u', 'U':
+		digits := 4
+		if ch == 'U' {
+			digits = 8
+		}
+		r, err = in.readEscapeDigits(first, 16, digits, digits, name)
+		if err != nil {
+			return 0, false, err
+		}
+		if !utf8.ValidRune(r) {
+			return 0, false, NewError(col,
+				fmt.Errorf("escape sequence is invalid Unicode code point %#x",
+					r))
+		}
+		return r, false, nil
+
+	case '0', '1', '2', '3', '4', '5', '6', '7':
+		in.UngetRune(ch)
+		r, err = in.readEscapeDigits(first, 8, 1, 3, name)
+		if err != nil {
+			return 0, false, err
+		}
+		if r > 255 {
+			return 0, false, NewError(col,
+				fmt.Errorf("octal escape value %d > 255", r))
+		}
+		return r, true, nil
+
+	default:
+		return 0, false, NewError(col,
+			fmt.Errorf("unexpected character '%c' in %s literal", ch, name))
+	}
+}
+
+// readEscapeDigits reads min to max digits of the base and returns
+// their value.
+func (in *Input) readEscapeDigits(first bool, base, min, max int,
+	name string) (rune, error) {
+
+	var value rune
+	for i := 0; i < max; i++ {
+		r, col, err := in.Rune(first)
+		if err != nil {
+			return 0, NewError(col, err)
+		}
+		var d rune
+		switch {
+		case '0' <= r && r <= '9':
+			d = r - '0'
+		case 'a' <= r && r <= 'f':
+			d = r - 'a' + 10
+		case 'A' <= r && r <= 'F':
+			d = r - 'A' + 10
+		default:
+			d = rune(base)
+		}
+		if d >= rune(base) {
+			if i >= min {
+				in.UngetRune(r)
+				break
+			}
+			return 0, NewError(col,
+				fmt.Errorf("unexpected character '%c' in %s literal",
+					r, name))
+		}
+		value = value*rune(base) + d
+	}
+	return value, nil
+}
+
+// readStringLiteral reads the double-quoted string literal. The
+// opening quote is already consumed.
+func (in *Input) readStringLiteral(first bool, col int) (*Token, error) {
+	var sb strings.Builder
+	for {
+		r, c, err := in.Rune(first)
+		if err != nil {
+			return nil, NewError(c, fmt.Errorf("unterminated string literal"))
+		}
+		switch r {
+		case '"':
+			return &Token{
+				Column: col,
+				Type:   TString,
+				StrVal: sb.String(),
+			}, nil
+		case '\\':
+			var isByte bool
+			r, isByte, err = in.readEscape(first, '"', "string")
+			if err != nil {
+				return nil, err
+			}
+			if isByte {
+				sb.WriteByte(byte(r))
+				continue
+			}
+		}
+		sb.WriteRune(r)
+	}
+}
+
+// evalString evaluates the binary operation where at least one of
+// the operands is a string. Strings support concatenation and
+// comparison with other strings.
+func (b binary) evalString(v1, v2 Value) (Value, error) {
+	s1, ok1 := v1.(StringValue)
+	s2, ok2 := v2.(StringValue)
+	if !ok1 || !ok2 {
+		return nil, NewError(b.col,
+			fmt.Errorf("unsupport values %s and %s for binary operand '%s'",
+				v1.Type(), v2.Type(), b.op))
+	}
+	switch b.op {
+	case '+':
+		return s1 + s2, nil
+	case TEq:
+		return BoolValue(s1 == s2), nil
+	case TNeq:
+		return BoolValue(s1 != s2), nil
+	case '<':
+		return BoolValue(s1 < s2), nil
+	case '>':
+		return BoolValue(s1 > s2), nil
+	case TLe:
+		return BoolValue(s1 <= s2), nil
+	case TGe:
+		return BoolValue(s1 >= s2), nil
+	default:
+		return nil, NewError(b.col,
+			fmt.Errorf("unsupport binary operand '%s' for strings", b.op))
+	}
+}
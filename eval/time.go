@@ -0,0 +1,334 @@
+//
+// Copyright (c) 2024 Markku Rossi
+//
+// All rights reserved.
+//
+
+package eval
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	_ Value = TimeValue{}
+	_ Expr  = TimeValue{}
+)
+
+// TimeValue implements points in time as Value. The times have
+// nanosecond precision and a time zone.
+type TimeValue struct {
+	t time.Time
+}
+
+// NewTimeValue creates a new time value.
+func NewTimeValue(t time.Time) TimeValue {
+	return TimeValue{
+		t: t,
+	}
+}
+
+// reTime matches the ISO-8601 and RFC-3339 date and time literals:
+// 2024-01-15, 2024-01-15T10:30, 2024-01-15T10:30:00.5Z, and
+// 2024-01-15T10:30:00+02:00.
+var reTime = regexp.MustCompile(`^(\d{4})-(\d{2})-(\d{2})(?:[Tt](\d{2}):(\d{2})(?::(\d{2})(?:\.(\d{1,9}))?)?([Zz]|[+-]\d{2}:?\d{2})?)?`)
+
+// ParseTime parses the ISO-8601 or RFC-3339 date and time. The times
+// without a time zone offset are in the location loc.
+func ParseTime(str string, loc *time.Location) (TimeValue, error) {
+	m := reTime.FindStringSubmatch(str)
+	if m == nil || len(m[0]) != len(str) {
+		return TimeValue{}, fmt.Errorf("invalid time '%s'", str)
+	}
+	var f [6]int
+	for i := range f {
+		f[i], _ = strconv.Atoi(m[i+1])
+	}
+	var nsec int
+	if len(m[7]) > 0 {
+		nsec, _ = strconv.Atoi(m[7] + strings.Repeat("0", 9-len(m[7])))
+	}
+	switch zone := m[8]; {
+	case zone == "Z" || zone == "z":
+		loc = time.UTC
+	case len(zone) > 0:
+		digits := strings.Replace(zone[1:], ":", "", 1)
+		h, _ := strconv.Atoi(digits[:2])
+		min, _ := strconv.Atoi(digits[2:])
+		if h > 23 || min > 59 {
+			return TimeValue{}, fmt.Errorf("invalid time '%s'", str)
+		}
+		offset := h*3600 + min*60
+		if zone[0] == '-' {
+			offset = -offset
+		}
+		loc = time.FixedZone("", offset)
+	}
+	if f[1] < 1 || f[1] > 12 || f[3] > 23 || f[4] > 59 || f[5] > 59 {
+		return TimeValue{}, fmt.Errorf("invalid time '%s'", str)
+	}
+	t := time.Date(f[0], time.Month(f[1]), f[2], f[3], f[4], f[5], nsec, loc)
+	if t.Day() != f[2] {
+		return TimeValue{}, fmt.Errorf("invalid time '%s'", str)
+	}
+	return TimeValue{
+		t: t,
+	}, nil
+}
+
+// ValueTime returns the value as a time. Numbers and time unit values
+// are converted from Unix seconds.
+func ValueTime(value Value) (TimeValue, error) {
+	if v, ok := value.(TimeValue); ok {
+		return v, nil
+	}
+	seconds, unit, err := unitOperand(value)
+	if err != nil || unit.Dims != dimsTime && !unit.Dims.IsZero() {
+		return TimeValue{},
+			fmt.Errorf("type conversion from %s to time failed", value.Type())
+	}
+	return unixTime(seconds)
+}
+
+// unixTime returns the Unix time seconds as a time in UTC.
+func unixTime(seconds *big.Rat) (TimeValue, error) {
+	epoch := TimeValue{
+		t: time.Unix(0, 0).UTC(),
+	}
+	return epoch.add(seconds)
+}
+
+// readTime reads the date and time literal starting with the rune
+// r. The function returns a nil token without consuming input if the
+// input does not contain a time literal.
+func (in *Input) readTime(first bool, col int, r rune) (*Token, error) {
+	if !isDecimalDigit(r) {
+		return nil, nil
+	}
+	n := len(in.line)
+	if n > 40 {
+		n = 40
+	}
+	lit := reTime.FindString(string(r) + string(in.line[:n]))
+	if len(lit) == 0 {
+		return nil, nil
+	}
+	count := len([]rune(lit)) - 1
+	if count < len(in.line) {
+		next := in.line[count]
+		if unicode.IsLetter(next) || unicode.IsDigit(next) || next == '_' ||
+			next == '.' {
+			return nil, nil
+		}
+	}
+	for i := 0; i < count; i++ {
+		in.Rune(first)
+	}
+	v, err := ParseTime(lit, in.config.Location)
+	if err != nil {
+		return nil, NewError(col, err)
+	}
+	return &Token{
+		Column:  col,
+		Type:    TTime,
+		TimeVal: v,
+	}, nil
+}
+
+// Time returns the time.
+func (v TimeValue) Time() time.Time {
+	return v.t
+}
+
+func (v TimeValue) String() string {
+	return v.t.Format(time.RFC3339Nano)
+}
+
+// Format implements Value.Format(). The times are printed in the
+// location of the options. In non-decimal bases, the times are
+// printed as Unix seconds.
+func (v TimeValue) Format(options Options) string {
+	t := v.t
+	if options.Location != nil {
+		t = t.In(options.Location)
+	}
+	if options.Encoding != EncodingNone {
+		return options.Encoding.Encode([]byte(t.Format(time.RFC3339Nano)))
+	}
+	if options.Human {
+		return t.Format(time.RFC1123)
+	}
+	if options.Base == Base10 && !options.String {
+		return t.Format(time.RFC3339Nano)
+	}
+	return formatInteger(big.NewInt(t.Unix()), 64, options)
+}
+
+// Type implements Value.Type().
+func (v TimeValue) Type() Type {
+	return TypeTime
+}
+
+// Eval implements Expr.Eval().
+func (v TimeValue) Eval(env *Env) (Value, error) {
+	return v, nil
+}
+
+var nsPerSecond = big.NewRat(1000000000, 1)
+
+// add adds the number of seconds to the time. The offset is
+// truncated towards zero to nanoseconds.
+func (v TimeValue) add(seconds *big.Rat) (TimeValue, error) {
+	ns := new(big.Rat).Mul(seconds, nsPerSecond)
+	n := new(big.Int).Quo(ns.Num(), ns.Denom())
+	sec, nsec := new(big.Int).QuoRem(n, big.NewInt(1000000000), new(big.Int))
+	sec.Add(sec, big.NewInt(v.t.Unix()))
+	if !sec.IsInt64() {
+		return TimeValue{}, fmt.Errorf("time out of range")
+	}
+	t := time.Unix(sec.Int64(), int64(v.t.Nanosecond())+nsec.Int64())
+	return TimeValue{
+		t: t.In(v.t.Location()),
+	}, nil
+}
+
+// sub returns the difference of the times in seconds.
+func (v TimeValue) sub(o TimeValue) *big.Rat {
+	sec := big.NewInt(v.t.Unix() - o.t.Unix())
+	ns := sec.Mul(sec, big.NewInt(1000000000))
+	ns.Add(ns, big.NewInt(int64(v.t.Nanosecond()-o.t.Nanosecond())))
+	return new(big.Rat).SetFrac(ns, big.NewInt(1000000000))
+}
+
+// evalTime evaluates the binary operation where at least one of the
+// operands is a time. Adding time unit values or numbers of seconds
+// to times moves the times, and the difference of two times is a
+// time unit value in seconds.
+func (b binary) evalTime(env *Env, v1, v2 Value) (Value, error) {
+	t1, ok1 := v1.(TimeValue)
+	t2, ok2 := v2.(TimeValue)
+
+	if ok1 && ok2 {
+		switch b.op {
+		case TEq, TNeq, '<', '>', TLe, TGe:
+			var cmp int
+			if t1.t.Before(t2.t) {
+				cmp = -1
+			} else if t1.t.After(t2.t) {
+				cmp = 1
+			}
+			return b.compareResult(cmp), nil
+
+		case '-':
+			return unitResult(env, t1.sub(t2), units["s"]), nil
+		}
+	} else if b.op == '+' || b.op == '-' && ok1 {
+		t, other := t1, v2
+		if ok2 {
+			t, other = t2, v1
+		}
+		seconds, unit, err := unitOperand(other)
+		if err == nil && (unit.Dims == dimsTime || unit.Dims.IsZero()) {
+			if b.op == '-' {
+				seconds = new(big.Rat).Neg(seconds)
+			}
+			result, err := t.add(seconds)
+			if err != nil {
+				return nil, NewError(b.col, err)
+			}
+			return result, nil
+		}
+	}
+	return nil, NewError(b.col,
+		fmt.Errorf("unsupport values %s and %s for binary operand '%s'",
+			v1.Type(), v2.Type(), b.op))
+}
+
+// unixScale returns the number of seconds in the Unix time unit.
+func (bi *Builtin) unixScale(env *Env, idx int) (*big.Rat, error) {
+	if len(bi.args) <= idx {
+		return big.NewRat(1, 1), nil
+	}
+	name, err := bi.stringArg(env, idx)
+	if err != nil {
+		return nil, err
+	}
+	switch name {
+	case "s", "ms", "us", "ns":
+		return units[name].Scale, nil
+	default:
+		return nil, NewError(bi.col,
+			fmt.Errorf("%s: invalid unit '%s', expected s, ms, us, or ns",
+				bi.name, name))
+	}
+}
+
+func builtinNow(bi *Builtin, env *Env) (Value, error) {
+	return TimeValue{
+		t: time.Now().In(env.Config().Location),
+	}, nil
+}
+
+func builtinDate(bi *Builtin, env *Env) (Value, error) {
+	v, err := bi.args[0].Eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch v := v.(type) {
+	case TimeValue:
+		return v, nil
+	case StringValue:
+		t, err := ParseTime(string(v), env.Config().Location)
+		if err != nil {
+			return nil, NewError(bi.col, fmt.Errorf("%s: %s", bi.name, err))
+		}
+		return t, nil
+	}
+	scale, err := bi.unixScale(env, 1)
+	if err != nil {
+		return nil, err
+	}
+	if v.Type() != TypeUnit && v.Type() != TypeDuration {
+		r, err := ValueRat(v)
+		if err != nil {
+			return nil, NewError(bi.col, fmt.Errorf("%s: %s", bi.name, err))
+		}
+		v = UnitValue{
+			r:    new(big.Rat).Mul(r, scale),
+			unit: units["s"],
+		}
+	}
+	t, err := ValueTime(v)
+	if err != nil {
+		return nil, NewError(bi.col, fmt.Errorf("%s: %s", bi.name, err))
+	}
+	return TimeValue{
+		t: t.t.In(env.Config().Location),
+	}, nil
+}
+
+func builtinUnix(bi *Builtin, env *Env) (Value, error) {
+	v, err := bi.args[0].Eval(env)
+	if err != nil {
+		return nil, err
+	}
+	t, ok := v.(TimeValue)
+	if !ok {
+		return nil, NewError(bi.col,
+			fmt.Errorf("%s: not a time: %s", bi.name, v))
+	}
+	scale, err := bi.unixScale(env, 1)
+	if err != nil {
+		return nil, err
+	}
+	r := t.sub(TimeValue{t: time.Unix(0, 0)})
+	r.Quo(r, scale)
+	i := new(big.Int).Div(r.Num(), r.Denom())
+	return bi.intResult(env, i, false)
+}
@@ -0,0 +1,489 @@
+//
+// Copyright (c) 2020-2024 Markku Rossi
+//
+// All rights reserved.
+//
+
+package eval
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// Type defines the supported primitive types.
+type Type int
+
+// Supported primitive types.
+const (
+	TypeBool Type = iota
+	TypeInt8
+	TypeUint8
+	TypeInt16
+	TypeUint16
+	TypeInt32
+	TypeUint32
+	TypeInt64
+	TypeUint64
+	TypeInt128
+	TypeUint128
+	TypeRational
+	TypeFloat64
+	TypeBigFloat
+	TypeComplex
+	TypeFixed
+	TypeUnit
+	TypeString
+	TypeIP
+	TypeCIDR
+	TypeMAC
+	TypeTime
+	TypeDuration
+)
+
+var typeNames = map[Type]string{
+	TypeBool:     "bool",
+	TypeInt8:     "int8",
+	TypeUint8:    "uint8",
+	TypeInt16:    "int16",
+	TypeUint16:   "uint16",
+	TypeInt32:    "int32",
+	TypeUint32:   "uint32",
+	TypeInt64:    "int64",
+	TypeUint64:   "uint64",
+	TypeInt128:   "int128",
+	TypeUint128:  "uint128",
+	TypeRational: "rational",
+	TypeFloat64:  "float64",
+	TypeBigFloat: "mpfloat",
+	TypeComplex:  "complex",
+	TypeFixed:    "fixed",
+	TypeUnit:     "unit",
+	TypeString:   "string",
+	TypeIP:       "ip",
+	TypeCIDR:     "cidr",
+	TypeMAC:      "mac",
+	TypeTime:     "time",
+	TypeDuration: "duration",
+}
+
+func (t Type) String() string {
+	name, ok := typeNames[t]
+	if ok {
+		return name
+	}
+	return fmt.Sprintf("{Type %d}", t)
+}
+
+// ConversionType returns the type conversion type for the argument
+// values i.e. the smallest type that is capable to represent both
+// argument values.
+func ConversionType(value1, value2 Value) (Type, error) {
+	t1 := value1.Type()
+	t2 := value2.Type()
+	if t1 > t2 {
+		return t1, nil
+	}
+	return t2, nil
+}
+
+// IntType returns the integer type of the word size bits and
+// signedness.
+func IntType(bits uint, signed bool) (Type, error) {
+	var t Type
+	switch bits {
+	case 8:
+		t = TypeInt8
+	case 16:
+		t = TypeInt16
+	case 32:
+		t = TypeInt32
+	case 64:
+		t = TypeInt64
+	case 128:
+		t = TypeInt128
+	default:
+		return 0, fmt.Errorf("invalid word size %d", bits)
+	}
+	if !signed {
+		t++
+	}
+	return t, nil
+}
+
+// IntBits returns the word size and signedness of the integer
+// type. The word size is 0 if the type is not an integer type.
+func IntBits(t Type) (bits uint, signed bool) {
+	switch t {
+	case TypeInt8:
+		return 8, true
+	case TypeUint8:
+		return 8, false
+	case TypeInt16:
+		return 16, true
+	case TypeUint16:
+		return 16, false
+	case TypeInt32:
+		return 32, true
+	case TypeUint32:
+		return 32, false
+	case TypeInt64:
+		return 64, true
+	case TypeUint64:
+		return 64, false
+	case TypeInt128:
+		return 128, true
+	case TypeUint128:
+		return 128, false
+	default:
+		return 0, false
+	}
+}
+
+// intRange returns the minimum and maximum values of the integer of
+// bits bits.
+func intRange(bits uint, signed bool) (min, max *big.Int) {
+	if !signed {
+		max = new(big.Int).Lsh(bigOne, bits)
+		return new(big.Int), max.Sub(max, bigOne)
+	}
+	max = new(big.Int).Lsh(bigOne, bits-1)
+	min = new(big.Int).Neg(max)
+	return min, max.Sub(max, bigOne)
+}
+
+// NewIntValue creates an integer value of the integer type t. The
+// integer number is wrapped to the word size of the type.
+func NewIntValue(t Type, i *big.Int) (Value, error) {
+	bits, signed := IntBits(t)
+	if bits == 0 {
+		return nil, fmt.Errorf("%s is not an integer type", t)
+	}
+	v := wrapBigInt(i, bits, signed)
+	switch t {
+	case TypeInt8:
+		return Int8Value(v.Int64()), nil
+	case TypeUint8:
+		return Uint8Value(v.Uint64()), nil
+	case TypeInt16:
+		return Int16Value(v.Int64()), nil
+	case TypeUint16:
+		return Uint16Value(v.Uint64()), nil
+	case TypeInt32:
+		return Int32Value(v.Int64()), nil
+	case TypeUint32:
+		return Uint32Value(v.Uint64()), nil
+	case TypeInt64:
+		return Int64Value(v.Int64()), nil
+	case TypeUint64:
+		return Uint64Value(v.Uint64()), nil
+	case TypeInt128:
+		return Int128Value{i: v}, nil
+	default:
+		return Uint128Value{i: v}, nil
+	}
+}
+
+// ValueBool returns the value as bool.
+func ValueBool(value Value) (bool, error) {
+	switch v := value.(type) {
+	case BoolValue:
+		return bool(v), nil
+	case Int8Value:
+		if v != 0 {
+			return true, nil
+		}
+		return false, nil
+	case Int16Value:
+		if v != 0 {
+			return true, nil
+		}
+		return false, nil
+	case Int32Value:
+		if v != 0 {
+			return true, nil
+		}
+		return false, nil
+	case Int64Value:
+		if v != 0 {
+			return true, nil
+		}
+		return false, nil
+	case Uint8Value, Uint16Value, Uint32Value, Uint64Value, Int128Value,
+		Uint128Value:
+		i, _ := ValueBigInt(value)
+		return i.Sign() != 0, nil
+	case Float64Value:
+		if v != 0 {
+			return true, nil
+		}
+		return false, nil
+	case BigFloatValue:
+		if v.f.Sign() != 0 {
+			return true, nil
+		}
+		return false, nil
+	case RationalValue:
+		if v.r.Sign() != 0 {
+			return true, nil
+		}
+		return false, nil
+	case ComplexValue:
+		if v != 0 {
+			return true, nil
+		}
+		return false, nil
+	case FixedValue:
+		if v.raw != 0 {
+			return true, nil
+		}
+		return false, nil
+	}
+	return false, fmt.Errorf("type conversion from %T to bool failed", value)
+}
+
+// ValueInt8 returns the value as int8.
+func ValueInt8(value Value) (int8, error) {
+	switch v := value.(type) {
+	case BoolValue:
+		if v {
+			return int8(1), nil
+		}
+		return int8(0), nil
+	case Int8Value:
+		return int8(v), nil
+	case Int16Value:
+		return int8(v), nil
+	case Int32Value:
+		return int8(v), nil
+	case Int64Value:
+		return int8(v), nil
+	case Uint8Value, Uint16Value, Uint32Value, Uint64Value, Int128Value,
+		Uint128Value:
+		i, _ := ValueBigInt(value)
+		return int8(wrapInt(i, 64)), nil
+	}
+	return 0, fmt.Errorf("type conversion from %T to int8 failed", value)
+}
+
+// ValueInt16 returns the value as int16.
+func ValueInt16(value Value) (int16, error) {
+	switch v := value.(type) {
+	case BoolValue:
+		if v {
+			return int16(1), nil
+		}
+		return int16(0), nil
+	case Int8Value:
+		return int16(v), nil
+	case Int16Value:
+		return int16(v), nil
+	case Int32Value:
+		return int16(v), nil
+	case Int64Value:
+		return int16(v), nil
+	case Uint8Value, Uint16Value, Uint32Value, Uint64Value, Int128Value,
+		Uint128Value:
+		i, _ := ValueBigInt(value)
+		return int16(wrapInt(i, 64)), nil
+	}
+	return 0, fmt.Errorf("type conversion from %T to int16 failed", value)
+}
+
+// ValueInt32 returns the value as int32.
+func ValueInt32(value Value) (int32, error) {
+	switch v := value.(type) {
+	case BoolValue:
+		if v {
+			return int32(1), nil
+		}
+		return int32(0), nil
+	case Int8Value:
+		return int32(v), nil
+	case Int16Value:
+		return int32(v), nil
+	case Int32Value:
+		return int32(v), nil
+	case Int64Value:
+		return int32(v), nil
+	case Uint8Value, Uint16Value, Uint32Value, Uint64Value, Int128Value,
+		Uint128Value:
+		i, _ := ValueBigInt(value)
+		return int32(wrapInt(i, 64)), nil
+	}
+	return 0, fmt.Errorf("type conversion from %T to int32 failed", value)
+}
+
+// ValueInt64 returns the value as int64.
+func ValueInt64(value Value) (int64, error) {
+	switch v := value.(type) {
+	case BoolValue:
+		if v {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case Int8Value:
+		return int64(v), nil
+	case Int16Value:
+		return int64(v), nil
+	case Int32Value:
+		return int64(v), nil
+	case Int64Value:
+		return int64(v), nil
+	case Uint8Value, Uint16Value, Uint32Value, Uint64Value, Int128Value,
+		Uint128Value:
+		i, _ := ValueBigInt(value)
+		return int64(wrapInt(i, 64)), nil
+	}
+	return 0, fmt.Errorf("type conversion from %T to int64 failed", value)
+}
+
+// ValueBigInt returns the integer value as *big.Int.
+func ValueBigInt(value Value) (*big.Int, error) {
+	switch v := value.(type) {
+	case BoolValue:
+		if v {
+			return big.NewInt(1), nil
+		}
+		return big.NewInt(0), nil
+	case Int8Value:
+		return big.NewInt(int64(v)), nil
+	case Uint8Value:
+		return big.NewInt(int64(v)), nil
+	case Int16Value:
+		return big.NewInt(int64(v)), nil
+	case Uint16Value:
+		return big.NewInt(int64(v)), nil
+	case Int32Value:
+		return big.NewInt(int64(v)), nil
+	case Uint32Value:
+		return big.NewInt(int64(v)), nil
+	case Int64Value:
+		return big.NewInt(int64(v)), nil
+	case Uint64Value:
+		return new(big.Int).SetUint64(uint64(v)), nil
+	case Int128Value:
+		return new(big.Int).Set(v.i), nil
+	case Uint128Value:
+		return new(big.Int).Set(v.i), nil
+	case IPValue:
+		return new(big.Int).Set(v.i), nil
+	case MACValue:
+		return v.Int(), nil
+	}
+	return nil, fmt.Errorf("type conversion from %T to *big.Int failed",
+		value)
+}
+
+// ValueFloat64 returns the value as float64.
+func ValueFloat64(value Value) (float64, error) {
+	switch v := value.(type) {
+	case BoolValue:
+		if v {
+			return float64(1), nil
+		}
+		return float64(0), nil
+	case Int8Value:
+		return float64(v), nil
+	case Int16Value:
+		return float64(v), nil
+	case Int32Value:
+		return float64(v), nil
+	case Int64Value:
+		return float64(v), nil
+	case Float64Value:
+		return float64(v), nil
+	case Uint8Value, Uint16Value, Uint32Value, Uint64Value, Int128Value,
+		Uint128Value:
+		i, _ := ValueBigInt(value)
+		f, _ := new(big.Float).SetInt(i).Float64()
+		return f, nil
+	case RationalValue:
+		f, _ := v.r.Float64()
+		return f, nil
+	case FixedValue:
+		f, _ := v.Rat().Float64()
+		return f, nil
+	}
+	return 0, fmt.Errorf("type conversion from %T to float64 failed", value)
+}
+
+// ValueBigFloat returns the value as *big.Float.
+func ValueBigFloat(value Value) (*big.Float, error) {
+	switch v := value.(type) {
+	case BoolValue:
+		if v {
+			return big.NewFloat(1), nil
+		}
+		return big.NewFloat(0), nil
+	case Int8Value:
+		return big.NewFloat(float64(v)), nil
+	case Int16Value:
+		return big.NewFloat(float64(v)), nil
+	case Int32Value:
+		return big.NewFloat(float64(v)), nil
+	case Int64Value:
+		return big.NewFloat(float64(v)), nil
+	case Float64Value:
+		return big.NewFloat(float64(v)), nil
+	case BigFloatValue:
+		return v.f, nil
+	case Uint8Value, Uint16Value, Uint32Value, Uint64Value, Int128Value,
+		Uint128Value:
+		i, _ := ValueBigInt(value)
+		return new(big.Float).SetInt(i), nil
+	case RationalValue:
+		return new(big.Float).SetPrec(DefaultPrecision).SetRat(v.r), nil
+	}
+	return nil, fmt.Errorf("type conversion from %T to *big.Float failed",
+		value)
+}
+
+// ValueRat returns the value as *big.Rat.
+func ValueRat(value Value) (*big.Rat, error) {
+	switch v := value.(type) {
+	case BoolValue:
+		if v {
+			return big.NewRat(1, 1), nil
+		}
+		return big.NewRat(0, 1), nil
+	case Int8Value:
+		return big.NewRat(int64(v), 1), nil
+	case Int16Value:
+		return big.NewRat(int64(v), 1), nil
+	case Int32Value:
+		return big.NewRat(int64(v), 1), nil
+	case Int64Value:
+		return big.NewRat(int64(v), 1), nil
+	case Uint8Value, Uint16Value, Uint32Value, Uint64Value, Int128Value,
+		Uint128Value:
+		i, _ := ValueBigInt(value)
+		return new(big.Rat).SetInt(i), nil
+	case Float64Value:
+		if math.IsInf(float64(v), 0) || math.IsNaN(float64(v)) {
+			break
+		}
+		// The shortest decimal representation converts 0.1 to 1/10.
+		r, _ := new(big.Rat).SetString(strconv.FormatFloat(float64(v),
+			'g', -1, 64))
+		return r, nil
+	case BigFloatValue:
+		if v.f.IsInf() {
+			break
+		}
+		r, _ := new(big.Rat).SetString(v.f.Text('g', -1))
+		return r, nil
+	case RationalValue:
+		return new(big.Rat).Set(v.r), nil
+	case FixedValue:
+		return v.Rat(), nil
+	case UnitValue:
+		return new(big.Rat).Set(v.r), nil
+	case DurationValue:
+		return new(big.Rat).Set(v.r), nil
+	}
+	return nil, fmt.Errorf("type conversion from %s to *big.Rat failed",
+		value)
+}
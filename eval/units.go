@@ -0,0 +1,710 @@
+//
+// Copyright (c) 2024 Markku Rossi
+//
+// All rights reserved.
+//
+
+package eval
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	_ Value = UnitValue{}
+	_ Expr  = UnitValue{}
+)
+
+// Dims defines the dimensions of a unit as the exponents of the base
+// quantities information and time. The canonical units of the base
+// quantities are byte (B) and second (s).
+type Dims struct {
+	Info int
+	Time int
+}
+
+// IsZero tests if the dimensions are zero i.e. the unit is
+// dimensionless.
+func (d Dims) IsZero() bool {
+	return d.Info == 0 && d.Time == 0
+}
+
+// Add returns the dimensions of the product of two quantities.
+func (d Dims) Add(o Dims) Dims {
+	return Dims{
+		Info: d.Info + o.Info,
+		Time: d.Time + o.Time,
+	}
+}
+
+// Sub returns the dimensions of the quotient of two quantities.
+func (d Dims) Sub(o Dims) Dims {
+	return Dims{
+		Info: d.Info - o.Info,
+		Time: d.Time - o.Time,
+	}
+}
+
+// Mul returns the dimensions of the quantity raised to the power n.
+func (d Dims) Mul(n int) Dims {
+	return Dims{
+		Info: d.Info * n,
+		Time: d.Time * n,
+	}
+}
+
+// Unit defines a unit of measurement.
+type Unit struct {
+	// Name is the unit symbol.
+	Name string
+	// Scale is the size of the unit in canonical units.
+	Scale *big.Rat
+	// Dims are the unit dimensions.
+	Dims Dims
+}
+
+func (u *Unit) String() string {
+	return u.Name
+}
+
+var (
+	dimsNone = Dims{}
+	dimsInfo = Dims{Info: 1}
+	dimsTime = Dims{Time: 1}
+	dimsFreq = Dims{Time: -1}
+	dimsRate = Dims{Info: 1, Time: -1}
+)
+
+var units = make(map[string]*Unit)
+
+var siPrefixes = []string{"k", "M", "G", "T", "P", "E"}
+
+func defineUnit(name string, scale *big.Rat, dims Dims) {
+	units[name] = &Unit{
+		Name:  name,
+		Scale: scale,
+		Dims:  dims,
+	}
+}
+
+// defineSI defines the unit and its SI decimal prefixed multiples.
+func defineSI(name string, scale *big.Rat, dims Dims) {
+	defineUnit(name, scale, dims)
+	for i, prefix := range siPrefixes {
+		defineUnit(prefix+name, new(big.Rat).Mul(scale, pow(1000, i+1)),
+			dims)
+	}
+}
+
+// defineIEC defines the IEC binary prefixed multiples of the unit.
+func defineIEC(name string, scale *big.Rat, dims Dims) {
+	for i, prefix := range []string{"Ki", "Mi", "Gi", "Ti", "Pi", "Ei"} {
+		defineUnit(prefix+name, new(big.Rat).Mul(scale, pow(1024, i+1)),
+			dims)
+	}
+}
+
+func init() {
+	// Dimensionless SI multipliers.
+	for i, prefix := range siPrefixes {
+		defineUnit(prefix, pow(1000, i+1), dimsNone)
+	}
+
+	// Information.
+	defineSI("B", big.NewRat(1, 1), dimsInfo)
+	defineIEC("B", big.NewRat(1, 1), dimsInfo)
+	defineUnit("KB", pow(1000, 1), dimsInfo)
+	defineSI("bit", big.NewRat(1, 8), dimsInfo)
+	defineIEC("bit", big.NewRat(1, 8), dimsInfo)
+
+	// Time.
+	defineUnit("ns", pow(1000, -3), dimsTime)
+	defineUnit("us", pow(1000, -2), dimsTime)
+	defineUnit("µs", pow(1000, -2), dimsTime)
+	defineUnit("ms", pow(1000, -1), dimsTime)
+	defineUnit("s", big.NewRat(1, 1), dimsTime)
+	defineUnit("m", big.NewRat(60, 1), dimsTime)
+	defineUnit("min", big.NewRat(60, 1), dimsTime)
+	defineUnit("h", big.NewRat(3600, 1), dimsTime)
+	defineUnit("d", big.NewRat(86400, 1), dimsTime)
+
+	// Frequency and data rate.
+	defineSI("Hz", big.NewRat(1, 1), dimsFreq)
+	defineSI("bps", big.NewRat(1, 8), dimsRate)
+}
+
+// pow returns base**exp as a rational number.
+func pow(base int64, exp int) *big.Rat {
+	p := new(big.Int).Exp(big.NewInt(base), big.NewInt(int64(abs(exp))), nil)
+	if exp < 0 {
+		return new(big.Rat).SetFrac(big.NewInt(1), p)
+	}
+	return new(big.Rat).SetInt(p)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// LookupUnit returns the unit by its name.
+func LookupUnit(name string) (*Unit, bool) {
+	u, ok := units[name]
+	return u, ok
+}
+
+// canonicalUnit returns the unit of the dimensions in canonical
+// units: B, s, Hz, B/s, B^2, etc.
+func canonicalUnit(dims Dims) *Unit {
+	var name string
+	switch dims {
+	case dimsInfo:
+		name = "B"
+	case dimsTime:
+		name = "s"
+	case dimsFreq:
+		name = "Hz"
+	case dimsRate:
+		name = "B/s"
+	default:
+		var num, den []string
+		for _, d := range []struct {
+			name string
+			exp  int
+		}{
+			{"B", dims.Info},
+			{"s", dims.Time},
+		} {
+			var term string
+			switch abs(d.exp) {
+			case 0:
+				continue
+			case 1:
+				term = d.name
+			default:
+				term = fmt.Sprintf("%s^%d", d.name, abs(d.exp))
+			}
+			if d.exp > 0 {
+				num = append(num, term)
+			} else {
+				den = append(den, term)
+			}
+		}
+		if len(num) == 0 {
+			num = append(num, "1")
+		}
+		name = strings.Join(num, "*")
+		if len(den) > 0 {
+			name += "/" + strings.Join(den, "*")
+		}
+	}
+	return &Unit{
+		Name:  name,
+		Scale: big.NewRat(1, 1),
+		Dims:  dims,
+	}
+}
+
+// readSuffix reads the unit suffix following a number literal. If
+// the input does not continue with a known unit, readSuffix returns
+// nil and does not consume any input. Time units followed by digits
+// end the suffix so that compound durations like 1h30m can be read.
+func (in *Input) readSuffix() *Unit {
+	var runes []rune
+	for {
+		r, _, err := in.Rune(false)
+		if err != nil {
+			break
+		}
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			in.UngetRune(r)
+			break
+		}
+		if isDecimalDigit(r) {
+			unit, ok := units[string(runes)]
+			if ok && unit.Dims == dimsTime {
+				in.UngetRune(r)
+				return unit
+			}
+		}
+		runes = append(runes, r)
+	}
+	unit, ok := units[string(runes)]
+	if !ok {
+		for i := len(runes) - 1; i >= 0; i-- {
+			in.UngetRune(runes[i])
+		}
+		return nil
+	}
+	return in.readUnitTerms(unit)
+}
+
+// readUnitTerms reads the '*' and '/' unit terms following the unit
+// suffix without spaces, for example the /s of 100MB/s. The unit
+// names are resolved only in number suffixes so that the undefined
+// variables are not mistaken for units.
+func (in *Input) readUnitTerms(unit *Unit) *Unit {
+	for {
+		op, _, err := in.Rune(false)
+		if err != nil {
+			return unit
+		}
+		if op != '*' && op != '/' {
+			in.UngetRune(op)
+			return unit
+		}
+		runes := []rune{op}
+		for {
+			r, _, err := in.Rune(false)
+			if err != nil {
+				break
+			}
+			if !unicode.IsLetter(r) {
+				in.UngetRune(r)
+				break
+			}
+			runes = append(runes, r)
+		}
+		term, ok := units[string(runes[1:])]
+		if !ok || term.Dims.IsZero() {
+			for i := len(runes) - 1; i >= 0; i-- {
+				in.UngetRune(runes[i])
+			}
+			return unit
+		}
+		if op == '*' {
+			unit = &Unit{
+				Name:  unit.Name + "*" + term.Name,
+				Scale: new(big.Rat).Mul(unit.Scale, term.Scale),
+				Dims:  unit.Dims.Add(term.Dims),
+			}
+		} else {
+			unit = &Unit{
+				Name:  unit.Name + "/" + term.Name,
+				Scale: new(big.Rat).Quo(unit.Scale, term.Scale),
+				Dims:  unit.Dims.Sub(term.Dims),
+			}
+		}
+	}
+}
+
+// scaleLiteral scales the decimal number literal by the unit
+// suffix. Units with dimensions result in a unit token. Dimensionless
+// multipliers result in an integer token if the scaled value is an
+// integer number and in a float token otherwise.
+func (in *Input) scaleLiteral(col int, literal string, unit *Unit) (
+	*Token, error) {
+
+	r, ok := new(big.Rat).SetString(literal)
+	if !ok {
+		return nil, NewError(col,
+			fmt.Errorf("invalid number literal: %s", literal))
+	}
+	if unit.Dims == dimsTime {
+		return in.readDuration(col, r.Mul(r, unit.Scale))
+	}
+	if !unit.Dims.IsZero() {
+		return &Token{
+			Column:  col,
+			Type:    TUnit,
+			UnitVal: NewUnitValue(r, unit),
+		}, nil
+	}
+	r.Mul(r, unit.Scale)
+	if r.IsInt() {
+		v, err := in.config.IntValue(r.Num(), false)
+		if err != nil {
+			return nil, NewError(col, fmt.Errorf("integer overflow in %s%s",
+				literal, unit.Name))
+		}
+		return &Token{
+			Column: col,
+			Type:   TInteger,
+			IntVal: v.(Expr),
+		}, nil
+	}
+	return &Token{
+		Column: col,
+		Type:   TFloat,
+		FloatVal: BigFloatValue{
+			f: in.config.NewFloat().SetRat(r),
+		},
+	}, nil
+}
+
+// ParseUnit parses the unit expression from the input string.
+func ParseUnit(input string) (*Unit, error) {
+	in := NewStringInput(input)
+	unit, err := NewParser(in).ParseUnit()
+	if err != nil {
+		return nil, err
+	}
+	err = in.ExpectEOL()
+	if err != nil {
+		return nil, err
+	}
+	return unit, nil
+}
+
+// ParseUnit parses a unit expression of the form UNIT {('*'|'/')
+// UNIT}, for example MiB/s.
+func (p *Parser) ParseUnit() (*Unit, error) {
+	var result *Unit
+	var op TokenType
+
+	for {
+		t, err := p.in.GetToken()
+		if err != nil {
+			return nil, err
+		}
+		if t.Type != TIdentifier {
+			return nil,
+				NewError(t.Column, fmt.Errorf("unexpected token '%s'", t))
+		}
+		unit, ok := units[t.StrVal]
+		if !ok || unit.Dims.IsZero() {
+			return nil, NewError(t.Column, fmt.Errorf("unknown unit '%s'", t))
+		}
+		switch op {
+		case 0:
+			result = unit
+		case '*':
+			result = &Unit{
+				Name:  result.Name + "*" + unit.Name,
+				Scale: new(big.Rat).Mul(result.Scale, unit.Scale),
+				Dims:  result.Dims.Add(unit.Dims),
+			}
+		default:
+			result = &Unit{
+				Name:  result.Name + "/" + unit.Name,
+				Scale: new(big.Rat).Quo(result.Scale, unit.Scale),
+				Dims:  result.Dims.Sub(unit.Dims),
+			}
+		}
+		if !p.in.HasToken() {
+			return result, nil
+		}
+		t, err = p.in.GetToken()
+		if err != nil {
+			return nil, err
+		}
+		if t.Type != '*' && t.Type != '/' {
+			p.in.UngetToken(t)
+			return result, nil
+		}
+		op = t.Type
+	}
+}
+
+// UnitValue implements numbers with units of measurement as
+// Value. The value is stored in canonical units and it is printed in
+// its display unit.
+type UnitValue struct {
+	r    *big.Rat
+	unit *Unit
+}
+
+// NewUnitValue creates a new unit value for the number v expressed
+// in the unit.
+func NewUnitValue(v *big.Rat, unit *Unit) UnitValue {
+	return UnitValue{
+		r:    new(big.Rat).Mul(v, unit.Scale),
+		unit: unit,
+	}
+}
+
+// Unit returns the value's display unit.
+func (v UnitValue) Unit() *Unit {
+	return v.unit
+}
+
+// Rat returns the value in its display unit.
+func (v UnitValue) Rat() *big.Rat {
+	return new(big.Rat).Quo(v.r, v.unit.Scale)
+}
+
+func (v UnitValue) String() string {
+	return formatRat(v.Rat()) + " " + v.unit.Name
+}
+
+// Format implements Value.Format().
+func (v UnitValue) Format(options Options) string {
+	if options.Human {
+		f, _ := v.r.Float64()
+		switch v.unit.Dims {
+		case dimsInfo:
+			return formatHumanSize(f, options)
+		case dimsRate:
+			return formatHumanSize(f, options) + "/s"
+		case dimsTime:
+			return formatHumanTime(f, options)
+		}
+	}
+	if options.Base != Base10 && v.r.IsInt() && v.r.Num().IsInt64() {
+		return formatInt(v.r.Num().Int64(), 64, options) + " " +
+			canonicalUnit(v.unit.Dims).Name
+	}
+	return options.Locale.localize(formatRat(v.Rat())) + " " + v.unit.Name
+}
+
+// Type implements Value.Type().
+func (v UnitValue) Type() Type {
+	return TypeUnit
+}
+
+// Eval implements Expr.Eval().
+func (v UnitValue) Eval(env *Env) (Value, error) {
+	return v, nil
+}
+
+// Convert converts the value to the unit. Numbers without units are
+// interpreted in canonical units.
+func Convert(value Value, unit *Unit) (Value, error) {
+	r, u, err := unitOperand(value)
+	if err != nil {
+		return nil, err
+	}
+	if !u.Dims.IsZero() && u.Dims != unit.Dims {
+		return nil, fmt.Errorf("incompatible units %s and %s", u, unit)
+	}
+	return UnitValue{
+		r:    r,
+		unit: unit,
+	}, nil
+}
+
+// unitResult returns the value r in the unit. If the unit is
+// dimensionless, the result is a plain number.
+func unitResult(env *Env, r *big.Rat, unit *Unit) Value {
+	if !unit.Dims.IsZero() {
+		return newQuantity(r, unit)
+	}
+	if r.IsInt() {
+		v, err := env.Config().IntValue(r.Num(), false)
+		if err == nil {
+			return v
+		}
+	}
+	return BigFloatValue{
+		f: env.Config().NewFloat().SetRat(r),
+	}
+}
+
+// unitOperand returns the operand value in canonical units and its
+// unit. Durations are in seconds and numbers without units are
+// dimensionless.
+func unitOperand(v Value) (*big.Rat, *Unit, error) {
+	r, err := ValueRat(v)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch v := v.(type) {
+	case UnitValue:
+		return r, v.unit, nil
+	case DurationValue:
+		return r, units["s"], nil
+	default:
+		return r, canonicalUnit(dimsNone), nil
+	}
+}
+
+// evalUnit evaluates the binary operation where at least one of the
+// operands is a unit value. The additive operations need operands of
+// the same dimensions. In the remainder and comparisons, numbers
+// without units are interpreted in canonical units.
+func (b binary) evalUnit(env *Env, v1, v2 Value) (Value, error) {
+	r1, u1, err := unitOperand(v1)
+	if err != nil {
+		return nil, NewError(b.col, err)
+	}
+	r2, u2, err := unitOperand(v2)
+	if err != nil {
+		return nil, NewError(b.col, err)
+	}
+
+	switch b.op {
+	case '+', '-', '%', TEq, TNeq, '<', '>', TLe, TGe:
+		if (b.op == '+' || b.op == '-') &&
+			u1.Dims.IsZero() != u2.Dims.IsZero() {
+			unit := u1
+			if u1.Dims.IsZero() {
+				unit = u2
+			}
+			return nil, NewError(b.col,
+				fmt.Errorf("incompatible unit %s and dimensionless number "+
+					"for '%s'", unit, b.op))
+		}
+		unit := u1
+		if u1.Dims.IsZero() {
+			unit = u2
+		} else if !u2.Dims.IsZero() {
+			if u1.Dims != u2.Dims {
+				return nil, NewError(b.col,
+					fmt.Errorf("incompatible units %s and %s for '%s'",
+						u1, u2, b.op))
+			}
+			if u2.Scale.Cmp(u1.Scale) < 0 {
+				unit = u2
+			}
+		}
+		switch b.op {
+		case '+':
+			return unitResult(env, new(big.Rat).Add(r1, r2), unit), nil
+
+		case '-':
+			return unitResult(env, new(big.Rat).Sub(r1, r2), unit), nil
+
+		case '%':
+			if r2.Sign() == 0 {
+				return nil, NewError(b.col, errDivideByZero)
+			}
+			return unitResult(env, ratMod(r1, r2), unit), nil
+		}
+		cmp := r1.Cmp(r2)
+		switch b.op {
+		case TEq:
+			return BoolValue(cmp == 0), nil
+		case TNeq:
+			return BoolValue(cmp != 0), nil
+		case '<':
+			return BoolValue(cmp < 0), nil
+		case '>':
+			return BoolValue(cmp > 0), nil
+		case TLe:
+			return BoolValue(cmp <= 0), nil
+		default:
+			return BoolValue(cmp >= 0), nil
+		}
+
+	case '*':
+		unit := u1
+		if u1.Dims.IsZero() {
+			unit = u2
+		} else if !u2.Dims.IsZero() {
+			unit = canonicalUnit(u1.Dims.Add(u2.Dims))
+		}
+		return unitResult(env, new(big.Rat).Mul(r1, r2), unit), nil
+
+	case '/':
+		if r2.Sign() == 0 {
+			return nil, NewError(b.col, errDivideByZero)
+		}
+		var unit *Unit
+		dims := u1.Dims.Sub(u2.Dims)
+		if u2.Dims.IsZero() {
+			unit = u1
+		} else if u1.Dims.IsZero() || dims.IsZero() ||
+			strings.ContainsAny(u2.Name, "*/") {
+			unit = canonicalUnit(dims)
+		} else {
+			unit = &Unit{
+				Name:  u1.Name + "/" + u2.Name,
+				Scale: new(big.Rat).Quo(u1.Scale, u2.Scale),
+				Dims:  dims,
+			}
+		}
+		return unitResult(env, new(big.Rat).Quo(r1, r2), unit), nil
+
+	case TPower:
+		if !u2.Dims.IsZero() {
+			return nil, NewError(b.col,
+				fmt.Errorf("exponent must be dimensionless: %s", v2))
+		}
+		if !r2.IsInt() || !r2.Num().IsInt64() {
+			return nil, NewError(b.col,
+				fmt.Errorf("unit exponent must be an integer: %s", v2))
+		}
+		n := r2.Num().Int64()
+		if n < -64 || n > 64 {
+			return nil, NewError(b.col,
+				fmt.Errorf("unit exponent out of range: %d", n))
+		}
+		result := big.NewRat(1, 1)
+		for i := 0; i < abs(int(n)); i++ {
+			result.Mul(result, r1)
+		}
+		if n < 0 {
+			if result.Sign() == 0 {
+				return nil, NewError(b.col, errDivideByZero)
+			}
+			result.Inv(result)
+		}
+		return unitResult(env, result, canonicalUnit(u1.Dims.Mul(int(n)))), nil
+
+	default:
+		return nil, NewError(b.col,
+			fmt.Errorf("unsupported binary operand '%s' for units", b.op))
+	}
+}
+
+// formatRat formats the rational number as a decimal number with up
+// to 15 significant digits.
+func formatRat(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	f, _ := r.Float64()
+	f, _ = strconv.ParseFloat(strconv.FormatFloat(f, 'g', 15, 64), 64)
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+var iecUnits = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+
+// formatHumanNumber formats the number v as a byte size or, if the
+// options select human readable times, as a time in seconds.
+func formatHumanNumber(v float64, options Options) string {
+	if options.HumanTime {
+		return formatHumanTime(v, options)
+	}
+	return formatHumanSize(v, options)
+}
+
+// formatHumanSize formats the byte size v with the largest IEC unit
+// which keeps the value at least 1.
+func formatHumanSize(v float64, options Options) string {
+	if math.IsInf(v, 0) || math.IsNaN(v) {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	var unit int
+	for unit+1 < len(iecUnits) && math.Abs(v) >= 1024 {
+		v /= 1024
+		unit++
+	}
+	return formatHuman(v, options) + " " + iecUnits[unit]
+}
+
+var timeUnits = []string{"d", "h", "min", "s", "ms", "us", "ns"}
+
+// formatHumanTime formats the time v in seconds with the largest time
+// unit which keeps the value at least 1.
+func formatHumanTime(v float64, options Options) string {
+	if math.IsInf(v, 0) || math.IsNaN(v) || v == 0 {
+		return strconv.FormatFloat(v, 'f', -1, 64) + " s"
+	}
+	for _, name := range timeUnits {
+		scale, _ := units[name].Scale.Float64()
+		if math.Abs(v) >= scale || name == "ns" {
+			return formatHuman(v/scale, options) + " " + name
+		}
+	}
+	return ""
+}
+
+// formatHuman formats the value v with two decimals, trimming
+// trailing zeros.
+func formatHuman(v float64, options Options) string {
+	str := strconv.FormatFloat(v, 'f', 2, 64)
+	str = strings.TrimRight(str, "0")
+	str = strings.TrimSuffix(str, ".")
+	if str == "-0" {
+		str = "0"
+	}
+	return options.Locale.localize(str)
+}
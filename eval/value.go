@@ -0,0 +1,694 @@
+//
+// Copyright (c) 2020, 2024 Markku Rossi
+//
+// All rights reserved.
+//
+
+package eval
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	_ Value = BoolValue(false)
+	_ Value = Int8Value(0)
+	_ Value = Int16Value(0)
+	_ Value = Int32Value(0)
+	_ Value = Int64Value(0)
+	_ Value = Uint8Value(0)
+	_ Value = Uint16Value(0)
+	_ Value = Uint32Value(0)
+	_ Value = Uint64Value(0)
+	_ Value = Int128Value{}
+	_ Value = Uint128Value{}
+	_ Value = Float64Value(0)
+	_ Value = BigFloatValue{
+		f: big.NewFloat(0),
+	}
+)
+
+// Value implements a value.
+type Value interface {
+	String() string
+	Format(options Options) string
+	Type() Type
+}
+
+// Options define value output options.
+type Options struct {
+	Base      Base
+	String    bool
+	Human     bool
+	HumanTime bool
+	Locale    *Locale
+	Rational  RationalFormat
+	Digits    int
+	Rounding  big.RoundingMode
+	Complex   ComplexFormat
+	Raw       bool
+	// Magnitude specifies if negative integers are printed in
+	// non-decimal bases as signed magnitude -0x2a instead of their
+	// two's complement bit pattern.
+	Magnitude bool
+	// Encoding specifies the binary-to-text encoding of strings and
+	// integers. The integers are encoded as big-endian bytes.
+	Encoding Encoding
+	// MAC specifies the notation of MAC addresses.
+	MAC MACFormat
+	// Location specifies the time zone of time values. The nil
+	// location prints the times in their own time zones.
+	Location *time.Location
+}
+
+// CheckFormat returns an error if the value can't be printed in the
+// output format of the options.
+func CheckFormat(value Value, options Options) error {
+	switch value.(type) {
+	case DurationValue:
+		if options.String || options.Base == BaseHexFloat ||
+			options.Encoding != EncodingNone {
+			return fmt.Errorf("unsupported output format for %s",
+				value.Type())
+		}
+	}
+	return nil
+}
+
+// Base defines the output base for numbers.
+type Base int
+
+// Supported output bases.
+const (
+	Base2 Base = iota
+	Base8
+	Base10
+	Base16
+	BaseBinary
+	BaseHexFloat
+)
+
+var baseNames = map[Base]string{
+	Base2:        "2",
+	Base8:        "8",
+	Base10:       "10",
+	Base16:       "16",
+	BaseBinary:   "2",
+	BaseHexFloat: "16",
+}
+
+func (b Base) String() string {
+	name, ok := baseNames[b]
+	if ok {
+		return name
+	}
+	return fmt.Sprintf("{base %d}", b)
+}
+
+var basePrefixes = map[Base]string{
+	Base2:        "0b",
+	Base8:        "0",
+	Base10:       "",
+	Base16:       "0x",
+	BaseBinary:   "",
+	BaseHexFloat: "0x",
+}
+
+// Prefix returns the base prefix string.
+func (b Base) Prefix() string {
+	prefix, ok := basePrefixes[b]
+	if ok {
+		return prefix
+	}
+	return ""
+}
+
+var bases = map[Base]int{
+	Base2:        2,
+	Base8:        8,
+	Base10:       10,
+	Base16:       16,
+	BaseBinary:   2,
+	BaseHexFloat: 16,
+}
+
+// Base returns the base as integer number.
+func (b Base) Base() int {
+	base, ok := bases[b]
+	if ok {
+		return base
+	}
+	return 10
+}
+
+var formats = map[Base]byte{
+	Base2:        'b',
+	Base8:        'f',
+	Base10:       'f',
+	Base16:       'x',
+	BaseBinary:   'b',
+	BaseHexFloat: 'x',
+}
+
+// FloatFormat returns base format for the strconv.FormatFloat
+// function.
+func (b Base) FloatFormat() byte {
+	fmt, ok := formats[b]
+	if ok {
+		return fmt
+	}
+	return 'f'
+}
+
+func stringify(v int64, base Base) string {
+	var result string
+
+	for i := 56; i >= 0; i -= 8 {
+		b := (v >> i) & 0xff
+		if b == 0 {
+			if len(result) > 0 {
+				result += "."
+			}
+		} else {
+			result += string(rune(b))
+		}
+	}
+
+	return result
+}
+
+// formatInt formats the integer number of the word size bits.
+func formatInt(v int64, bits uint, options Options) string {
+	return formatInteger(big.NewInt(v), bits, options)
+}
+
+// formatInteger formats the integer number of the word size bits. In
+// non-decimal bases, negative numbers are printed as their two's
+// complement bit pattern unless the options select the signed
+// magnitude format.
+func formatInteger(i *big.Int, bits uint, options Options) string {
+	if options.String {
+		return stringify(wrapInt(i, 64), options.Base)
+	}
+	if options.Human {
+		f, _ := new(big.Float).SetInt(i).Float64()
+		return formatHumanNumber(f, options)
+	}
+	if options.Encoding != EncodingNone {
+		return options.Encoding.Encode(intBytes(i, bits))
+	}
+	if options.Base == Base10 {
+		return options.Locale.localize(i.String())
+	}
+	if i.Sign() < 0 {
+		if options.Magnitude {
+			return "-" + options.Base.Prefix() +
+				new(big.Int).Neg(i).Text(options.Base.Base())
+		}
+		i = new(big.Int).Add(i, new(big.Int).Lsh(bigOne, bits))
+	}
+	return options.Base.Prefix() + i.Text(options.Base.Base())
+}
+
+// formatFloat formats the binary floating point number r in the
+// base 2, 8, or 16 fixed point notation. Since the binary floating
+// point numbers have a finite expansion in these bases, the number is
+// printed exactly unless the options limit the number of fraction
+// digits.
+func formatFloat(r *big.Rat, options Options) string {
+	base := options.Base.Base()
+	digits := options.Digits
+	if digits <= 0 {
+		// The denominator is 2**k; each digit holds log2(base) bits.
+		k := r.Denom().BitLen() - 1
+		var bits int
+		for b := base; b > 1; b >>= 1 {
+			bits++
+		}
+		digits = (k + bits - 1) / bits
+	}
+	str := formatRatBase(r, base, digits, options.Rounding)
+	if options.Digits <= 0 && strings.IndexByte(str, '.') >= 0 {
+		str = strings.TrimRight(str, "0")
+		str = strings.TrimSuffix(str, ".")
+	}
+	// The octal fractions need the 0o prefix to read back.
+	prefix := options.Base.Prefix()
+	if options.Base == Base8 {
+		prefix = "0o"
+	}
+	if strings.HasPrefix(str, "-") {
+		return "-" + prefix + str[1:]
+	}
+	return prefix + str
+}
+
+// BoolValue implements bool values as Value.
+type BoolValue bool
+
+func (v BoolValue) String() string {
+	if v {
+		return "true"
+	}
+	return "false"
+}
+
+// Format implements Value.Format().
+func (v BoolValue) Format(options Options) string {
+	return v.String()
+}
+
+// Type implements Value.Type().
+func (v BoolValue) Type() Type {
+	return TypeBool
+}
+
+// Eval implements Expr.Eval().
+func (v BoolValue) Eval(env *Env) (Value, error) {
+	return v, nil
+}
+
+// Int8Value implements int values as Value.
+type Int8Value int
+
+func (v Int8Value) String() string {
+	return strconv.FormatInt(int64(v), 10)
+}
+
+// Format implements Value.Format().
+func (v Int8Value) Format(options Options) string {
+	return formatInt(int64(v), 8, options)
+}
+
+// Type implements Value.Type().
+func (v Int8Value) Type() Type {
+	return TypeInt8
+}
+
+// Eval implements Expr.Eval().
+func (v Int8Value) Eval(env *Env) (Value, error) {
+	return v, nil
+}
+
+// Int16Value implements int values as Value.
+type Int16Value int
+
+func (v Int16Value) String() string {
+	return strconv.FormatInt(int64(v), 10)
+}
+
+// Format implements Value.Format().
+func (v Int16Value) Format(options Options) string {
+	return formatInt(int64(v), 16, options)
+}
+
+// Type implements Value.Type().
+func (v Int16Value) Type() Type {
+	return TypeInt16
+}
+
+// Eval implements Expr.Eval().
+func (v Int16Value) Eval(env *Env) (Value, error) {
+	return v, nil
+}
+
+// Int32Value implements int values as Value.
+type Int32Value int
+
+func (v Int32Value) String() string {
+	return strconv.FormatInt(int64(v), 10)
+}
+
+// Format implements Value.Format().
+func (v Int32Value) Format(options Options) string {
+	return formatInt(int64(v), 32, options)
+}
+
+// Type implements Value.Type().
+func (v Int32Value) Type() Type {
+	return TypeInt32
+}
+
+// Eval implements Expr.Eval().
+func (v Int32Value) Eval(env *Env) (Value, error) {
+	return v, nil
+}
+
+// Int64Value implements int64 values as Value.
+type Int64Value int64
+
+func (v Int64Value) String() string {
+	return strconv.FormatInt(int64(v), 10)
+}
+
+// Format implements Value.Format().
+func (v Int64Value) Format(options Options) string {
+	return formatInt(int64(v), 64, options)
+}
+
+// Type implements Value.Type().
+func (v Int64Value) Type() Type {
+	return TypeInt64
+}
+
+// Eval implements Expr.Eval().
+func (v Int64Value) Eval(env *Env) (Value, error) {
+	return v, nil
+}
+
+// Uint8Value implements uint8 values as Value.
+type Uint8Value uint8
+
+func (v Uint8Value) String() string {
+	return strconv.FormatUint(uint64(v), 10)
+}
+
+// Format implements Value.Format().
+func (v Uint8Value) Format(options Options) string {
+	return formatInteger(new(big.Int).SetUint64(uint64(v)), 8, options)
+}
+
+// Type implements Value.Type().
+func (v Uint8Value) Type() Type {
+	return TypeUint8
+}
+
+// Eval implements Expr.Eval().
+func (v Uint8Value) Eval(env *Env) (Value, error) {
+	return v, nil
+}
+
+// Uint16Value implements uint16 values as Value.
+type Uint16Value uint16
+
+func (v Uint16Value) String() string {
+	return strconv.FormatUint(uint64(v), 10)
+}
+
+// Format implements Value.Format().
+func (v Uint16Value) Format(options Options) string {
+	return formatInteger(new(big.Int).SetUint64(uint64(v)), 16, options)
+}
+
+// Type implements Value.Type().
+func (v Uint16Value) Type() Type {
+	return TypeUint16
+}
+
+// Eval implements Expr.Eval().
+func (v Uint16Value) Eval(env *Env) (Value, error) {
+	return v, nil
+}
+
+// Uint32Value implements uint32 values as Value.
+type Uint32Value uint32
+
+func (v Uint32Value) String() string {
+	return strconv.FormatUint(uint64(v), 10)
+}
+
+// Format implements Value.Format().
+func (v Uint32Value) Format(options Options) string {
+	return formatInteger(new(big.Int).SetUint64(uint64(v)), 32, options)
+}
+
+// Type implements Value.Type().
+func (v Uint32Value) Type() Type {
+	return TypeUint32
+}
+
+// Eval implements Expr.Eval().
+func (v Uint32Value) Eval(env *Env) (Value, error) {
+	return v, nil
+}
+
+// Uint64Value implements uint64 values as Value.
+type Uint64Value uint64
+
+func (v Uint64Value) String() string {
+	return strconv.FormatUint(uint64(v), 10)
+}
+
+// Format implements Value.Format().
+func (v Uint64Value) Format(options Options) string {
+	return formatInteger(new(big.Int).SetUint64(uint64(v)), 64, options)
+}
+
+// Type implements Value.Type().
+func (v Uint64Value) Type() Type {
+	return TypeUint64
+}
+
+// Eval implements Expr.Eval().
+func (v Uint64Value) Eval(env *Env) (Value, error) {
+	return v, nil
+}
+
+// Int128Value implements signed 128-bit integer values as Value.
+type Int128Value struct {
+	i *big.Int
+}
+
+// NewInt128Value creates a new Int128Value from the argument number. The
+// number is wrapped to 128 bits.
+func NewInt128Value(i *big.Int) Int128Value {
+	return Int128Value{
+		i: wrapBigInt(i, 128, true),
+	}
+}
+
+// Int returns the value as *big.Int.
+func (v Int128Value) Int() *big.Int {
+	return v.i
+}
+
+func (v Int128Value) String() string {
+	return v.i.String()
+}
+
+// Format implements Value.Format().
+func (v Int128Value) Format(options Options) string {
+	return formatInteger(v.i, 128, options)
+}
+
+// Type implements Value.Type().
+func (v Int128Value) Type() Type {
+	return TypeInt128
+}
+
+// Eval implements Expr.Eval().
+func (v Int128Value) Eval(env *Env) (Value, error) {
+	return v, nil
+}
+
+// Uint128Value implements unsigned 128-bit integer values as Value.
+type Uint128Value struct {
+	i *big.Int
+}
+
+// NewUint128Value creates a new Uint128Value from the argument number. The
+// number is wrapped to 128 bits.
+func NewUint128Value(i *big.Int) Uint128Value {
+	return Uint128Value{
+		i: wrapBigInt(i, 128, false),
+	}
+}
+
+// Int returns the value as *big.Int.
+func (v Uint128Value) Int() *big.Int {
+	return v.i
+}
+
+func (v Uint128Value) String() string {
+	return v.i.String()
+}
+
+// Format implements Value.Format().
+func (v Uint128Value) Format(options Options) string {
+	return formatInteger(v.i, 128, options)
+}
+
+// Type implements Value.Type().
+func (v Uint128Value) Type() Type {
+	return TypeUint128
+}
+
+// Eval implements Expr.Eval().
+func (v Uint128Value) Eval(env *Env) (Value, error) {
+	return v, nil
+}
+
+// Float64Value implements float64 values as Value.
+type Float64Value float64
+
+func (v Float64Value) String() string {
+	return strconv.FormatFloat(float64(v), 'f', -1, 64)
+}
+
+// Format implements Value.Format().
+func (v Float64Value) Format(options Options) string {
+	if options.String {
+		return stringify(int64(v), options.Base)
+	}
+	if options.Human {
+		return formatHumanNumber(float64(v), options)
+	}
+	if math.IsInf(float64(v), 0) || math.IsNaN(float64(v)) {
+		return strconv.FormatFloat(float64(v), 'f', -1, 64)
+	}
+	switch options.Base {
+	case Base10:
+		if options.Digits > 0 {
+			r := new(big.Rat).SetFloat64(float64(v))
+			return options.Locale.localize(formatRatDigits(r, options.Digits,
+				options.Rounding))
+		}
+		return options.Locale.localize(
+			strconv.FormatFloat(float64(v), 'f', -1, 64))
+
+	case BaseHexFloat:
+		return formatHexFloat(strconv.FormatFloat(float64(v), 'x', -1, 64))
+
+	default:
+		return formatFloat(new(big.Rat).SetFloat64(float64(v)), options)
+	}
+}
+
+// Type implements Value.Type().
+func (v Float64Value) Type() Type {
+	return TypeFloat64
+}
+
+// Eval implements Expr.Eval().
+func (v Float64Value) Eval(env *Env) (Value, error) {
+	return v, nil
+}
+
+// BigFloatValue implements big.Float values as Value.
+type BigFloatValue struct {
+	f *big.Float
+}
+
+// NewBigFloatValue creates a new BigFloatValue for the argument
+// number.
+func NewBigFloatValue(f *big.Float) BigFloatValue {
+	return BigFloatValue{
+		f: f,
+	}
+}
+
+// Float returns the value as *big.Float.
+func (v BigFloatValue) Float() *big.Float {
+	return v.f
+}
+
+func (v BigFloatValue) String() string {
+	return v.Format(Options{
+		Base: Base10,
+	})
+}
+
+// Format implements Value.Format().
+func (v BigFloatValue) Format(options Options) string {
+	if options.String {
+		ui64, _ := v.f.Uint64()
+		return stringify(int64(ui64), options.Base)
+	}
+	if options.Human {
+		f, _ := v.f.Float64()
+		return formatHumanNumber(f, options)
+	}
+	if v.f.IsInf() {
+		return v.f.Text('f', -1)
+	}
+	switch options.Base {
+	case Base10:
+		return options.Locale.localize(formatBigFloat(v.f, options))
+
+	case BaseHexFloat:
+		return formatHexFloat(v.f.Text('x', -1))
+
+	default:
+		f := v.f
+		if options.Digits <= 0 && f.Prec() > bigFloatBits {
+			f = new(big.Float).SetPrec(bigFloatBits).SetMode(options.Rounding).
+				Set(f)
+		}
+		r, _ := f.Rat(nil)
+		return formatFloat(r, options)
+	}
+}
+
+// bigFloatDigits defines the number of significant digits in the
+// automatic decimal output of mpfloat values.
+const bigFloatDigits = 34
+
+// bigFloatBits defines the number of significant bits in the
+// automatic binary, octal, and hexadecimal output of mpfloat
+// values. It matches the bigFloatDigits decimal digits.
+const bigFloatBits = 113
+
+// formatHexFloat removes the zero padding from the exponent of the
+// hexadecimal floating point number so that it matches the C99 %a
+// format: 0x1.8p+03 is formatted as 0x1.8p+3.
+func formatHexFloat(str string) string {
+	idx := strings.IndexByte(str, 'p')
+	if idx < 0 || idx+2 >= len(str) {
+		return str
+	}
+	exp := strings.TrimLeft(str[idx+2:], "0")
+	if len(exp) == 0 {
+		exp = "0"
+	}
+	return str[:idx+2] + exp
+}
+
+// formatBigFloat formats the value as a decimal number. If the
+// options do not specify the number of fraction digits, the value is
+// printed with up to bigFloatDigits significant digits.
+func formatBigFloat(f *big.Float, options Options) string {
+	if f.IsInf() {
+		return f.Text('f', -1)
+	}
+	r, _ := f.Rat(nil)
+	if options.Digits > 0 {
+		return formatRatDigits(r, options.Digits, options.Rounding)
+	}
+	str := f.Text('f', -1)
+	idx := strings.IndexByte(str, '.')
+	if idx < 0 || len(str)-idx-1 <= bigFloatDigits {
+		return str
+	}
+	// Number of fraction digits for bigFloatDigits significant
+	// digits.
+	e := f.Text('e', 0)
+	exp, _ := strconv.Atoi(e[strings.IndexByte(e, 'e')+1:])
+	digits := bigFloatDigits - 1 - exp
+	if digits < 0 {
+		digits = 0
+	}
+	str = formatRatDigits(r, digits, options.Rounding)
+	if strings.IndexByte(str, '.') >= 0 {
+		str = strings.TrimRight(str, "0")
+		str = strings.TrimSuffix(str, ".")
+	}
+	if str == "-0" {
+		str = "0"
+	}
+	return str
+}
+
+// Type implements Value.Type().
+func (v BigFloatValue) Type() Type {
+	return TypeBigFloat
+}
+
+// Eval implements Expr.Eval().
+func (v BigFloatValue) Eval(env *Env) (Value, error) {
+	return v, nil
+}
@@ -0,0 +1,115 @@
+//
+// Copyright (c) 2024 Markku Rossi
+//
+// All rights reserved.
+//
+
+//go:build ignore
+// +build ignore
+
+// Command gen_blocks generates the unicode_blocks.go table from the
+// Unicode Character Database Blocks.txt file. The file is read from
+// the Unicode site or, with the -file option, from a local copy:
+//
+//	go run gen_blocks.go -file /path/to/Blocks.txt
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	reVersion = regexp.MustCompile(`^# Blocks-(\d+\.\d+\.\d+)\.txt`)
+	reBlock   = regexp.MustCompile(`^([0-9A-F]+)\.\.([0-9A-F]+); (.+)$`)
+)
+
+func main() {
+	version := flag.String("version", "14.0.0", "Unicode version")
+	file := flag.String("file", "", "local Blocks.txt file")
+	flag.Parse()
+
+	var in io.Reader
+	if len(*file) > 0 {
+		f, err := os.Open(*file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		in = f
+	} else {
+		url := fmt.Sprintf("https://www.unicode.org/Public/%s/ucd/Blocks.txt",
+			*version)
+		resp, err := http.Get(url)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			log.Fatalf("%s: %s", url, resp.Status)
+		}
+		in = resp.Body
+	}
+
+	var buf bytes.Buffer
+	var fileVersion string
+	var count int
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if m := reVersion.FindStringSubmatch(line); m != nil {
+			fileVersion = m[1]
+			continue
+		}
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		m := reBlock.FindStringSubmatch(line)
+		if m == nil {
+			log.Fatalf("invalid block line: %s", line)
+		}
+		first, _ := strconv.ParseUint(m[1], 16, 32)
+		last, _ := strconv.ParseUint(m[2], 16, 32)
+		fmt.Fprintf(&buf, "\t{0x%04X, 0x%04X, %q},\n", first, last, m[3])
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatal(err)
+	}
+	if len(fileVersion) == 0 {
+		log.Fatal("Blocks.txt version not found")
+	}
+	if count == 0 {
+		log.Fatal("no blocks found")
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, `// Code generated by gen_blocks.go from Blocks-%s.txt. DO NOT EDIT.
+
+package main
+
+// unicodeBlocks lists the Unicode blocks in the code point order.
+var unicodeBlocks = []unicodeBlock{
+%s}
+`, fileVersion, buf.String())
+
+	data, err := format.Source(src.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile("unicode_blocks.go", data, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
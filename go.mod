@@ -4,6 +4,8 @@ go 1.14
 
 require (
 	github.com/markkurossi/tabulate v0.0.0-20201024065729-d5f6c78c6504
-	github.com/mattn/go-runewidth v0.0.9 // indirect
+	github.com/mattn/go-runewidth v0.0.9
 	github.com/peterh/liner v1.2.0
+	golang.org/x/crypto v0.14.0
+	golang.org/x/text v0.13.0
 )
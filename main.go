@@ -1,5 +1,5 @@
 //
-// Copyright (c) 2020, 2024 Markku Rossi
+// Copyright (c) 2020-2024 Markku Rossi
 //
 // All rights reserved.
 //
@@ -11,8 +11,11 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
+	"github.com/markkurossi/calc/eval"
 	"github.com/peterh/liner"
 )
 
@@ -25,12 +28,56 @@ type Command struct {
 }
 
 var (
-	input    *Input
+	input    *eval.Input
+	env      *eval.Env
 	commands []Command
 )
 
 func init() {
 	commands = append(commands, []Command{
+		{
+			Name:  "convert",
+			Title: "Convert value to unit",
+			Help: `convert EXPRESSION to UNIT
+
+Convert the value of the EXPRESSION to the UNIT. The UNIT can be a
+quotient or product of units, for example:
+
+  convert 100Mbit/s to MiB/s
+  convert 1d to min
+
+Numbers without units are interpreted as bytes and seconds.`,
+			Func: cmdConvert,
+		},
+		{
+			Name:  "define",
+			Title: "Define a function",
+			Help: `define NAME(PARAM, ...) = EXPRESSION
+
+Define the function NAME with the parameters PARAM. The function
+returns the value of the EXPRESSION which can refer to the function
+parameters, variables, and other functions. For example:
+
+  define clamp(x, lo, hi) = x < lo ? lo : x > hi ? hi : x`,
+			Func: cmdDefine,
+		},
+		{
+			Name:  "encode",
+			Title: "Show encodings and Unicode properties of characters",
+			Help: `encode EXPRESSION
+
+Show the encodings and the Unicode properties of the string or
+character value of the EXPRESSION. Integer values are encoded as
+Unicode code points. The command shows the UTF-8, UTF-16, and UTF-32
+byte sequences, the display width, and the Go, C, JSON, HTML, URL
+path, and URL query escaped forms of the string. For each character, the command shows
+its Unicode name, general category, block, script, and display
+width. For example:
+
+  encode "naïve €"
+  encode 0x1f600`,
+			Func: cmdEncode,
+		},
 		{
 			Name:  "help",
 			Title: "Print help information",
@@ -43,14 +90,63 @@ func init() {
 
 Print the value of the EXPRESSION. The optional FORMAT specifies the
 output format:
-  b -- binary (base 2) format
-  o -- octal (base 8) format
-  x -- hexadecimal (base 16) format
-  t -- binary (base 2) format without '0b' prefix
-  c -- character value in different character constants
-  s -- character string`,
+  b     -- binary (base 2) format
+  o     -- octal (base 8) format
+  x     -- hexadecimal (base 16) format
+  t     -- binary (base 2) format without '0b' prefix
+  a     -- hexadecimal floating point format: 0x1.8p+0
+  c     -- character value in different character constants
+  s     -- character string
+  h     -- human readable sizes, durations, and times
+  ht    -- human readable times for numbers without units
+  q     -- fixed point numbers with their raw integer and Q format
+  rect  -- complex numbers in rectangular form: 3+4i
+  polar -- complex numbers in polar form: 5∠0.927
+  hex, base64, base32, url, qp
+        -- binary-to-text encoding of strings and integers
+  ip    -- IP address or CIDR block in all forms
+  mac   -- MAC address in all notations and its properties
+  colon, dash, dot, bare
+        -- MAC address notation
+  time  -- time in the time zone, UTC, and Unix time units
+
+The README describes the number, unit, string, address, and time
+literals and their arithmetic.`,
 			Func: cmdPrint,
 		},
+		{
+			Name:  "set",
+			Title: "Set variables and options",
+			Help: `set var NAME = EXPRESSION
+set SETTING VALUE
+set
+
+Set the variable NAME to the value of the EXPRESSION, or set the
+SETTING to VALUE. Without arguments, print the current settings.
+The supported settings are:
+  locale    -- decimal and grouping separators of numbers:
+               C, ch, de, en, es, fi, fr, it, nl, sv
+  exact     -- on: integer division produces exact rational numbers
+  rational  -- rational number format: fraction (3/2), mixed (1 1/2),
+               or decimal (1.5)
+  digits    -- fraction digits in decimal output, 0 for automatic
+  precision -- mantissa precision of mpfloat values in bits, 1024
+               by default
+  rounding  -- rounding mode of mpfloat values and decimal output:
+               ToNearestEven, ToNearestAway, ToZero, AwayFromZero,
+               ToNegativeInf, ToPositiveInf
+  saturate  -- on: fixed point arithmetic saturates on overflow,
+               off: it wraps around
+  wordsize  -- word size of integer values in bits: 8, 16, 32, 64,
+               or 128; integer arithmetic wraps around at the word size
+  signed    -- on: integer values are signed, off: unsigned
+  magnitude -- on: negative integers are printed in binary, octal,
+               and hexadecimal as signed magnitude -0x2a, off: as
+               their two's complement bit pattern 0xffffffffffffffd6
+  timezone  -- time zone of time literals and output: UTC, Local, or
+               an IANA time zone name such as "Europe/Helsinki"`,
+			Func: cmdSet,
+		},
 		{
 			Name:  "quit",
 			Title: "Exit calc",
@@ -87,19 +183,40 @@ func help() error {
 }
 
 func main() {
-	fmt.Println("calc - programmers' calculator")
-	fmt.Println("Type `help' for information about available commands.")
-
+	serve := flag.Bool("serve", false,
+		"serve JSON-RPC requests from stdin or from the socket")
+	socket := flag.String("socket", "", "Unix socket path for JSON-RPC server")
 	flag.Parse()
 
 	log.SetFlags(0)
 
+	if *serve {
+		var err error
+		if len(*socket) > 0 {
+			stop := make(chan os.Signal, 1)
+			signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+			err = serveSocket(*socket, stop)
+		} else {
+			err = serveStdio()
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	fmt.Println("calc - programmers' calculator")
+	fmt.Println("Type `help' for information about available commands.")
+
 	var err error
 
-	input, err = NewInput("(calc) ", liner.NewLiner())
+	env = eval.NewEnv()
+
+	input, err = eval.NewInput("(calc) ", liner.NewLiner())
 	if err != nil {
 		log.Fatal(err)
 	}
+	input.SetConfig(env.Config())
 	defer input.Close()
 
 	for {
@@ -131,7 +248,7 @@ func main() {
 		} else {
 			err := matches[0].Func()
 			if err != nil {
-				col := Column(err)
+				col := eval.Column(err)
 				if col > 0 {
 					var ind string
 
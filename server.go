@@ -0,0 +1,418 @@
+//
+// Copyright (c) 2024 Markku Rossi
+//
+// All rights reserved.
+//
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/markkurossi/calc/eval"
+)
+
+// JSON-RPC error codes.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcEvalError      = 1
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int           `json:"code"`
+	Message string        `json:"message"`
+	Data    *rpcErrorData `json:"data,omitempty"`
+}
+
+type rpcErrorData struct {
+	Column int `json:"column"`
+}
+
+func newRPCError(code int, err error) *rpcError {
+	e := &rpcError{
+		Code:    code,
+		Message: err.Error(),
+	}
+	var ie *eval.Error
+	if errors.As(err, &ie) {
+		e.Data = &rpcErrorData{
+			Column: ie.Col,
+		}
+	}
+	return e
+}
+
+type exprParams struct {
+	Expr   string `json:"expr"`
+	Format string `json:"format"`
+}
+
+type setParams struct {
+	Name string `json:"name"`
+	Expr string `json:"expr"`
+}
+
+type defineParams struct {
+	Definition string `json:"definition"`
+}
+
+type optionParams struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type completeParams struct {
+	Text string `json:"text"`
+}
+
+type evalResult struct {
+	Value string `json:"value"`
+	Type  string `json:"type"`
+}
+
+type functionInfo struct {
+	Name    string `json:"name"`
+	Title   string `json:"title"`
+	MinArgs int    `json:"minArgs"`
+	MaxArgs int    `json:"maxArgs"`
+}
+
+// session implements a JSON-RPC client session. Each session has its
+// own evaluation environment and variables.
+type session struct {
+	env *eval.Env
+}
+
+func newSession() *session {
+	return &session{
+		env: eval.NewEnv(),
+	}
+}
+
+func serveStdio() error {
+	return newSession().serve(os.Stdin, os.Stdout)
+}
+
+// serveSocket serves JSON-RPC sessions from the Unix socket path
+// until the stop channel receives a signal.
+func serveSocket(path string, stop <-chan os.Signal) error {
+	// Remove the socket of a previous server unless the server is
+	// still running.
+	fi, err := os.Lstat(path)
+	if err == nil && fi.Mode()&os.ModeSocket != 0 {
+		conn, err := net.Dial("unix", path)
+		if err == nil {
+			conn.Close()
+			return fmt.Errorf("%s: server already running", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+	defer listener.Close()
+
+	done := make(chan struct{})
+	go func() {
+		<-stop
+		close(done)
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-done:
+				return nil
+			default:
+				return err
+			}
+		}
+		go func(conn net.Conn) {
+			defer conn.Close()
+			err := newSession().serve(conn, conn)
+			if err != nil {
+				log.Printf("session: %s\n", err)
+			}
+		}(conn)
+	}
+}
+
+func (s *session) serve(r io.Reader, w io.Writer) error {
+	decoder := json.NewDecoder(r)
+	encoder := json.NewEncoder(w)
+
+	for {
+		var req rpcRequest
+		err := decoder.Decode(&req)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			var syntaxError *json.SyntaxError
+			if errors.As(err, &syntaxError) {
+				// The stream is out of sync, report error and
+				// close the session.
+				encoder.Encode(&rpcResponse{
+					JSONRPC: "2.0",
+					ID:      json.RawMessage("null"),
+					Error:   newRPCError(rpcParseError, err),
+				})
+			}
+			return err
+		}
+		result, rpcErr := s.dispatch(&req)
+		if len(req.ID) == 0 {
+			// Notification.
+			continue
+		}
+		err = encoder.Encode(&rpcResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Result:  result,
+			Error:   rpcErr,
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *session) dispatch(req *rpcRequest) (interface{}, *rpcError) {
+	if req.JSONRPC != "2.0" {
+		return nil, newRPCError(rpcInvalidRequest,
+			fmt.Errorf("unsupported JSON-RPC version '%s'", req.JSONRPC))
+	}
+	switch req.Method {
+	case "evaluate", "format":
+		var params exprParams
+		if err := s.params(req, &params); err != nil {
+			return nil, err
+		}
+		if req.Method == "format" && len(params.Format) == 0 {
+			return nil, newRPCError(rpcInvalidParams,
+				errors.New("format not specified"))
+		}
+		return s.evaluate(params.Expr, params.Format)
+
+	case "set":
+		var params setParams
+		if err := s.params(req, &params); err != nil {
+			return nil, err
+		}
+		return s.set(params.Name, params.Expr)
+
+	case "setOption":
+		var params optionParams
+		if err := s.params(req, &params); err != nil {
+			return nil, err
+		}
+		err := s.env.Config().Set(params.Name, params.Value)
+		if err != nil {
+			return nil, newRPCError(rpcInvalidParams, err)
+		}
+		return params.Value, nil
+
+	case "listOptions":
+		return s.env.Config().Settings(), nil
+
+	case "define":
+		var params defineParams
+		if err := s.params(req, &params); err != nil {
+			return nil, err
+		}
+		return s.define(params.Definition)
+
+	case "complete":
+		var params completeParams
+		if err := s.params(req, &params); err != nil {
+			return nil, err
+		}
+		return s.complete(params.Text), nil
+
+	case "listFunctions":
+		var result []functionInfo
+		for _, bi := range eval.Builtins() {
+			result = append(result, functionInfo{
+				Name:    bi.Name,
+				Title:   bi.Title,
+				MinArgs: bi.MinArgs,
+				MaxArgs: bi.MaxArgs,
+			})
+		}
+		return result, nil
+
+	case "listVariables":
+		result := make(map[string]evalResult)
+		for _, name := range s.env.Names() {
+			val, _ := s.env.Get(name)
+			result[name] = evalResult{
+				Value: val.String(),
+				Type:  val.Type().String(),
+			}
+		}
+		return result, nil
+
+	default:
+		return nil, newRPCError(rpcMethodNotFound,
+			fmt.Errorf("method '%s' not found", req.Method))
+	}
+}
+
+func (s *session) params(req *rpcRequest, v interface{}) *rpcError {
+	if len(req.Params) == 0 {
+		return newRPCError(rpcInvalidParams, errors.New("missing params"))
+	}
+	err := json.Unmarshal(req.Params, v)
+	if err != nil {
+		return newRPCError(rpcInvalidParams, err)
+	}
+	return nil
+}
+
+// eval parses and evaluates the expression in the session's
+// environment.
+func (s *session) eval(input string) (eval.Value, error) {
+	expr, err := eval.ParseConfig(input, s.env.Config())
+	if err != nil {
+		return nil, err
+	}
+	return expr.Eval(s.env)
+}
+
+func (s *session) evaluate(expr, format string) (interface{}, *rpcError) {
+	options := s.env.Config().Options()
+	if len(format) > 0 {
+		var err error
+		options, err = formatOptions(options, format)
+		if err != nil {
+			return nil, newRPCError(rpcInvalidParams, err)
+		}
+	}
+	val, err := s.eval(expr)
+	if err == nil {
+		err = eval.CheckFormat(val, options)
+	}
+	if err != nil {
+		return nil, newRPCError(rpcEvalError, err)
+	}
+	return &evalResult{
+		Value: val.Format(options),
+		Type:  val.Type().String(),
+	}, nil
+}
+
+func (s *session) set(name, expr string) (interface{}, *rpcError) {
+	if !isIdentifier(name) {
+		return nil, newRPCError(rpcInvalidParams,
+			fmt.Errorf("invalid variable name '%s'", name))
+	}
+	val, err := s.eval(expr)
+	if err != nil {
+		return nil, newRPCError(rpcEvalError, err)
+	}
+	s.env.Set(name, val)
+	return &evalResult{
+		Value: val.String(),
+		Type:  val.Type().String(),
+	}, nil
+}
+
+func (s *session) define(definition string) (interface{}, *rpcError) {
+	in := eval.NewStringInput(definition)
+	in.SetConfig(s.env.Config())
+	f, err := eval.NewParser(in).ParseDefinition()
+	if err == nil {
+		err = in.ExpectEOL()
+	}
+	if err == nil {
+		err = s.env.Define(f)
+	}
+	if err != nil {
+		return nil, newRPCError(rpcEvalError, err)
+	}
+	return f.String(), nil
+}
+
+// complete returns the completion candidates for the identifier at
+// the end of the text. The command names are completed for the first
+// word of the text, and function and variable names for all other
+// words.
+func (s *session) complete(text string) []string {
+	runes := []rune(text)
+	start := len(runes)
+	for start > 0 {
+		r := runes[start-1]
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		start--
+	}
+	prefix := string(runes[start:])
+
+	var names []string
+	if len(strings.TrimSpace(string(runes[:start]))) == 0 {
+		for _, cmd := range commands {
+			names = append(names, cmd.Name)
+		}
+	} else {
+		for _, bi := range eval.Builtins() {
+			names = append(names, bi.Name)
+		}
+		names = append(names, s.env.FunctionNames()...)
+		names = append(names, s.env.Names()...)
+	}
+
+	result := []string{}
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) {
+			result = append(result, name)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func isIdentifier(name string) bool {
+	for idx, r := range name {
+		if idx == 0 {
+			if !unicode.IsLetter(r) {
+				return false
+			}
+		} else if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			return false
+		}
+	}
+	return len(name) > 0
+}
@@ -0,0 +1,130 @@
+//
+// Copyright (c) 2024 Markku Rossi
+//
+// All rights reserved.
+//
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type serverTest struct {
+	in  string
+	out string
+}
+
+var serverTests = []serverTest{
+	{
+		in:  `{"jsonrpc":"2.0","id":1,"method":"set","params":{"name":"x","expr":"42"}}`,
+		out: `{"jsonrpc":"2.0","id":1,"result":{"value":"42","type":"int64"}}`,
+	},
+	{
+		in:  `{"jsonrpc":"2.0","id":2,"method":"evaluate","params":{"expr":"x+1"}}`,
+		out: `{"jsonrpc":"2.0","id":2,"result":{"value":"43","type":"int64"}}`,
+	},
+	{
+		in:  `{"jsonrpc":"2.0","id":3,"method":"format","params":{"expr":"x","format":"x"}}`,
+		out: `{"jsonrpc":"2.0","id":3,"result":{"value":"0x2a","type":"int64"}}`,
+	},
+	{
+		in:  `{"jsonrpc":"2.0","id":4,"method":"evaluate","params":{"expr":"x+y"}}`,
+		out: `{"jsonrpc":"2.0","id":4,"error":{"code":1,"message":"undefined variable 'y'","data":{"column":2}}}`,
+	},
+	{
+		in:  `{"jsonrpc":"2.0","id":5,"method":"complete","params":{"text":"print x+ran"}}`,
+		out: `{"jsonrpc":"2.0","id":5,"result":["random"]}`,
+	},
+	{
+		in:  `{"jsonrpc":"2.0","id":6,"method":"unknown"}`,
+		out: `{"jsonrpc":"2.0","id":6,"error":{"code":-32601,"message":"method 'unknown' not found"}}`,
+	},
+}
+
+func TestServer(t *testing.T) {
+	var in, expected []string
+	for _, test := range serverTests {
+		in = append(in, test.in)
+		expected = append(expected, test.out)
+	}
+	out := new(bytes.Buffer)
+	err := newSession().serve(strings.NewReader(strings.Join(in, "\n")), out)
+	if err != nil {
+		t.Fatalf("serve failed: %s", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != len(expected) {
+		t.Fatalf("got %d responses, expected %d", len(lines), len(expected))
+	}
+	for idx, line := range lines {
+		if line != expected[idx] {
+			t.Errorf("test %d: unexpected response %s, expected %s",
+				idx, line, expected[idx])
+		}
+	}
+}
+
+func TestServeSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "calc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "calc.sock")
+
+	// Leave a stale socket behind.
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	listener.Close()
+
+	stop := make(chan os.Signal, 1)
+	result := make(chan error)
+	go func() {
+		result <- serveSocket(path, stop)
+	}()
+
+	var conn net.Conn
+	for i := 0; i < 100; i++ {
+		conn, err = net.Dial("unix", path)
+		if err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("dial failed: %s", err)
+	}
+	_, err = conn.Write([]byte(`{"jsonrpc":"2.0","id":1,"method":"evaluate","params":{"expr":"1+2"}}` + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	expected := `{"jsonrpc":"2.0","id":1,"result":{"value":"3","type":"int64"}}`
+	if strings.TrimSpace(line) != expected {
+		t.Errorf("unexpected response '%s', expected '%s'", line, expected)
+	}
+
+	stop <- os.Interrupt
+	if err := <-result; err != nil {
+		t.Errorf("serve failed: %s", err)
+	}
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Errorf("socket %s not removed", path)
+	}
+}